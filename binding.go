@@ -0,0 +1,137 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// DecodeFunc type defines the function that binds the config item to the given object.
+type DecodeFunc func(Item, interface{}) error
+
+// Binding interface defines the live value of the config target.
+// The value is decoded again when the config target changes, and is swapped
+// atomically only if the decoding succeeds.
+type Binding interface {
+	// Target returns the name of the bound config target.
+	Target() string
+
+	// Get returns the latest successfully decoded value.
+	// The returned value has the same type as the object given to the Bind function,
+	// it must not be modified.
+	Get() interface{}
+
+	// Err returns the error of the latest reload, nil if the latest reload succeeded.
+	// If the latest reload failed, Get still returns the last good value.
+	Err() error
+
+	// Close cancels the subscription of the config target changes, the value is no
+	// longer updated after closing.
+	Close() error
+}
+
+// Bind loads the given config target, decodes it into the given object and returns
+// the live value that updates when the config target changes.
+// The given object must be a non-nil pointer, and it is returned by the Get method
+// until the first successful reload. If the given decoder is nil, FormatItem.Decode is used.
+// The config target is decoded by the Configurator.LoadWith method, so the default
+// values, the strict mode and the validator apply to the first load and all reloads.
+// The subscription is registered before the first load, so the changes during
+// binding are not lost.
+func Bind(c Configurator, target string, v interface{}, decoder DecodeFunc) (Binding, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, errors.New("configurator: bind to non-pointer or nil object")
+	}
+	if decoder == nil {
+		decoder = decodeItem
+	}
+
+	b := &binding{c: c, target: target, typ: rv.Elem().Type(), decoder: decoder}
+	b.err.Store(bindingError{})
+	b.cancel = c.Subscribe(target, b.update)
+	if err := c.LoadWith(target, v, decoder); err != nil {
+		b.cancel()
+		return nil, err
+	}
+	b.mutex.Lock()
+	// The value of a change during binding is newer than the first loaded value.
+	if !b.updated {
+		b.value.Store(v)
+	}
+	b.mutex.Unlock()
+	return b, nil
+}
+
+// The bindingError type wraps the error so that it can be stored in atomic.Value.
+type bindingError struct {
+	err error
+}
+
+// The binding type is a built-in implementation of the Binding interface.
+type binding struct {
+	c       Configurator
+	target  string
+	typ     reflect.Type
+	decoder DecodeFunc
+	cancel  func()
+	mutex   sync.Mutex
+	updated bool
+	value   atomic.Value
+	err     atomic.Value
+}
+
+// Target returns the name of the bound config target.
+func (b *binding) Target() string {
+	return b.target
+}
+
+// Get returns the latest successfully decoded value.
+func (b *binding) Get() interface{} {
+	return b.value.Load()
+}
+
+// Err returns the error of the latest reload, nil if the latest reload succeeded.
+func (b *binding) Err() error {
+	return b.err.Load().(bindingError).err
+}
+
+// Close cancels the subscription of the config target changes.
+func (b *binding) Close() error {
+	b.cancel()
+	return nil
+}
+
+// The update method decodes the changed config target and swaps the value.
+func (b *binding) update(change *Change) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if change.New == nil {
+		b.err.Store(bindingError{ErrNotFound})
+		return
+	}
+	v := reflect.New(b.typ).Interface()
+	if err := b.c.LoadWith(b.target, v, b.decoder); err != nil {
+		b.err.Store(bindingError{err})
+		return
+	}
+	b.value.Store(v)
+	b.err.Store(bindingError{})
+	b.updated = true
+}
@@ -0,0 +1,207 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBind(t *testing.T) {
+	type Server struct {
+		Port int `json:"port" yaml:"port"`
+	}
+
+	l := new(mapLoader)
+	l.set("server", `{"port": 80}`)
+	o := New().Use(l)
+
+	b, err := Bind(o, "server", new(Server), nil)
+	if err != nil {
+		t.Fatalf("Bind(): %s", err)
+	}
+	if got := b.Target(); got != "server" {
+		t.Fatalf("Binding.Target(): %s", got)
+	}
+	if got := b.Get().(*Server).Port; got != 80 {
+		t.Fatalf("Binding.Get(): %d", got)
+	}
+	if err := b.Err(); err != nil {
+		t.Fatalf("Binding.Err(): %s", err)
+	}
+
+	l.set("server", `port: 8080`)
+	if err := o.Reload("server"); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Get().(*Server).Port; got != 8080 {
+		t.Fatalf("Binding.Get(): %d", got)
+	}
+
+	// The last good value is retained when decoding fails.
+	l.set("server", `{"port": "invalid"}`)
+	if err := o.Reload("server"); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Get().(*Server).Port; got != 8080 {
+		t.Fatalf("Binding.Get(): %d", got)
+	}
+	if err := b.Err(); err == nil {
+		t.Fatal("Binding.Err(): nil")
+	}
+
+	l.set("server", "")
+	if err := o.Reload("server"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Err(); err != ErrNotFound {
+		t.Fatalf("Binding.Err(): %v", err)
+	}
+	if got := b.Get().(*Server).Port; got != 8080 {
+		t.Fatalf("Binding.Get(): %d", got)
+	}
+}
+
+func TestBindError(t *testing.T) {
+	l := new(mapLoader)
+	l.set("server", `{"port": "invalid"}`)
+	o := New().Use(l)
+
+	var v struct {
+		Port int `json:"port"`
+	}
+	if _, err := Bind(o, "server", v, nil); err == nil {
+		t.Fatal("Bind(): no error")
+	}
+	if _, err := Bind(o, "unknown", &v, nil); err != ErrNotFound {
+		t.Fatalf("Bind(): %v", err)
+	}
+	if _, err := Bind(o, "server", &v, nil); err == nil {
+		t.Fatal("Bind(): no error")
+	}
+	if _, err := Bind(o, "server", &v, func(Item, interface{}) error { return nil }); err != nil {
+		t.Fatalf("Bind(): %s", err)
+	}
+}
+
+func TestBindDecodePath(t *testing.T) {
+	type Server struct {
+		Host string `json:"host" default:"localhost"`
+		Port int    `json:"port" validate:"min=1"`
+	}
+
+	l := new(mapLoader)
+	l.set("server", `{"port": 80}`)
+	o := New().Use(l).SetValidator(NewValidator()).SetStrict(true)
+
+	b, err := Bind(o, "server", new(Server), nil)
+	if err != nil {
+		t.Fatalf("Bind(): %s", err)
+	}
+	if got := b.Get().(*Server); got.Host != "localhost" || got.Port != 80 {
+		t.Fatalf("Binding.Get(): %+v", got)
+	}
+
+	// The default values are also set on reloads.
+	l.set("server", `{"port": 8080}`)
+	if err := o.Reload("server"); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Get().(*Server); got.Host != "localhost" || got.Port != 8080 {
+		t.Fatalf("Binding.Get(): %+v", got)
+	}
+
+	// The validator and the strict mode apply to the reloads.
+	l.set("server", `{"port": 0}`)
+	if err := o.Reload("server"); err != nil {
+		t.Fatal(err)
+	}
+	var ve *ValidationError
+	if err := b.Err(); !errors.As(err, &ve) {
+		t.Fatalf("Binding.Err(): %v", err)
+	}
+	l.set("server", `{"port": 80, "unknown": 1}`)
+	if err := o.Reload("server"); err != nil {
+		t.Fatal(err)
+	}
+	var ke *UnknownKeysError
+	if err := b.Err(); !errors.As(err, &ke) || ke.Target != "server" {
+		t.Fatalf("Binding.Err(): %v", err)
+	}
+	if got := b.Get().(*Server); got.Port != 8080 {
+		t.Fatalf("Binding.Get(): %+v", got)
+	}
+}
+
+func TestBinding_Close(t *testing.T) {
+	type Server struct {
+		Port int `json:"port"`
+	}
+
+	l := new(mapLoader)
+	l.set("server", `{"port": 80}`)
+	o := New().Use(l)
+
+	b, err := Bind(o, "server", new(Server), nil)
+	if err != nil {
+		t.Fatalf("Bind(): %s", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Binding.Close(): %s", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Binding.Close(): %s", err)
+	}
+	if n := len(o.(*configurator).subscribers["server"]); n != 0 {
+		t.Fatalf("Binding.Close(): %d subscribers", n)
+	}
+	l.set("server", `{"port": 8080}`)
+	if err := o.Reload("server"); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Get().(*Server).Port; got != 80 {
+		t.Fatalf("Binding.Get(): %d", got)
+	}
+}
+
+func TestBindChangeDuringBinding(t *testing.T) {
+	type Server struct {
+		Port int `json:"port"`
+	}
+
+	l := new(mapLoader)
+	l.set("server", `{"port": 80}`)
+	o := New().Use(l)
+
+	// The change between the subscription and the first load is not lost.
+	var once bool
+	decoder := func(item Item, v interface{}) error {
+		if !once {
+			once = true
+			l.set("server", `{"port": 8080}`)
+			if err := o.Reload("server"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return AsFormatItem(item).Decode(v)
+	}
+	b, err := Bind(o, "server", new(Server), decoder)
+	if err != nil {
+		t.Fatalf("Bind(): %s", err)
+	}
+	if got := b.Get().(*Server).Port; got != 8080 {
+		t.Fatalf("Binding.Get(): %d", got)
+	}
+}
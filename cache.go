@@ -0,0 +1,150 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CacheOptions defines the options of the configurator cache.
+type CacheOptions struct {
+	// TTL is the time to live of the cache entries, zero means no expiration.
+	TTL time.Duration
+
+	// Size is the maximum number of the cache entries, zero means no limit.
+	// The least recently used entries are evicted first.
+	Size int
+}
+
+// The newCache function creates and returns a new cache instance.
+func newCache(options CacheOptions) *cache {
+	return &cache{
+		options: options,
+		list:    list.New(),
+		entries: make(map[string]*list.Element),
+		targets: make(map[string]map[string]bool),
+	}
+}
+
+// The cache type is a LRU cache of the loaded config items and the decoded values.
+// All entries of the same config target can be invalidated together.
+type cache struct {
+	mutex   sync.Mutex
+	options CacheOptions
+	list    *list.List
+	entries map[string]*list.Element
+	targets map[string]map[string]bool
+}
+
+// The cacheEntry type defines the cache entry.
+type cacheEntry struct {
+	key     string
+	target  string
+	value   interface{}
+	expires time.Time
+}
+
+// The get method returns the cached value of the given key.
+func (c *cache) get(key string) (interface{}, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(e)
+		return nil, false
+	}
+	c.list.MoveToFront(e)
+	return entry.value, true
+}
+
+// The set method caches the given value of the given config target.
+func (c *cache) set(target, key string, value interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if e, found := c.entries[key]; found {
+		c.remove(e)
+	}
+	entry := &cacheEntry{key: key, target: target, value: value}
+	if c.options.TTL > 0 {
+		entry.expires = time.Now().Add(c.options.TTL)
+	}
+	c.entries[key] = c.list.PushFront(entry)
+	if c.targets[target] == nil {
+		c.targets[target] = make(map[string]bool)
+	}
+	c.targets[target][key] = true
+
+	if c.options.Size > 0 {
+		for c.list.Len() > c.options.Size {
+			c.remove(c.list.Back())
+		}
+	}
+}
+
+// The invalidate method removes all cache entries of the given config target.
+func (c *cache) invalidate(target string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key := range c.targets[target] {
+		c.remove(c.entries[key])
+	}
+}
+
+// The invalidateName method removes all cache entries of the config targets
+// with the given name.
+// For example: Given "name", removes "name", "name.json", "name.yaml", etc.
+func (c *cache) invalidateName(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for target, keys := range c.targets {
+		if sameTarget(target, name) {
+			for key := range keys {
+				c.remove(c.entries[key])
+			}
+		}
+	}
+}
+
+// The purge method removes all cache entries.
+func (c *cache) purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.list.Init()
+	c.entries = make(map[string]*list.Element)
+	c.targets = make(map[string]map[string]bool)
+}
+
+// The remove method removes the given cache entry, the caller must hold the lock.
+func (c *cache) remove(e *list.Element) {
+	entry := c.list.Remove(e).(*cacheEntry)
+	delete(c.entries, entry.key)
+	if keys := c.targets[entry.target]; keys != nil {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(c.targets, entry.target)
+		}
+	}
+}
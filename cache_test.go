@@ -0,0 +1,157 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	c := newCache(CacheOptions{Size: 2})
+	c.set("a", "a", 1)
+	c.set("a", "a.json", 2)
+	if _, found := c.get("a"); !found {
+		t.Fatal("cache.get(): not found")
+	}
+	// The "a.json" is the least recently used entry.
+	c.set("b", "b", 3)
+	if _, found := c.get("a.json"); found {
+		t.Fatal("cache.get(): found")
+	}
+	if v, found := c.get("b"); !found || v != 3 {
+		t.Fatalf("cache.get(): %v", v)
+	}
+
+	c.invalidate("a")
+	if _, found := c.get("a"); found {
+		t.Fatal("cache.get(): found")
+	}
+	c.set("c.yaml", "c.yaml", 4)
+	c.invalidateName("c")
+	if _, found := c.get("c.yaml"); found {
+		t.Fatal("cache.get(): found")
+	}
+	c.purge()
+	if _, found := c.get("b"); found {
+		t.Fatal("cache.get(): found")
+	}
+
+	c = newCache(CacheOptions{TTL: time.Millisecond})
+	c.set("a", "a", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, found := c.get("a"); found {
+		t.Fatal("cache.get(): found")
+	}
+}
+
+func TestConfigurator_SetCache(t *testing.T) {
+	n := 0
+	l := LoaderFunc(func(target string) (Item, error) {
+		if target != "a" {
+			return nil, nil
+		}
+		n++
+		return NewItemFromString(`{"name":"a"}`), nil
+	})
+	o := New().Use(l).SetCache(&CacheOptions{})
+
+	type Value struct {
+		Name string `json:"name"`
+	}
+	for i := 0; i < 3; i++ {
+		v := new(Value)
+		if err := o.LoadJSON("a", v); err != nil {
+			t.Fatalf("Configurator.LoadJSON(): %s", err)
+		}
+		if v.Name != "a" {
+			t.Fatalf("Configurator.LoadJSON(): %s", v.Name)
+		}
+		if _, err := o.Load("a"); err != nil {
+			t.Fatalf("Configurator.Load(): %s", err)
+		}
+	}
+	if n != 1 {
+		t.Fatalf("Configurator.SetCache(): %d", n)
+	}
+
+	o.Invalidate("a")
+	if err := o.LoadInto("a", new(Value)); err != nil {
+		t.Fatalf("Configurator.LoadInto(): %s", err)
+	}
+	o.Purge()
+	if _, err := o.Load("a"); err != nil {
+		t.Fatalf("Configurator.Load(): %s", err)
+	}
+	if _, err := o.Load("unknown"); err != ErrNotFound {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("Configurator.SetCache(): %d", n)
+	}
+
+	o.SetCache(nil)
+	if _, err := o.Load("a"); err != nil {
+		t.Fatalf("Configurator.Load(): %s", err)
+	}
+	if _, err := o.Load("a"); err != nil {
+		t.Fatalf("Configurator.Load(): %s", err)
+	}
+	if n != 5 {
+		t.Fatalf("Configurator.SetCache(): %d", n)
+	}
+}
+
+func TestConfigurator_CacheWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.json")
+	if err := ioutil.WriteFile(path, []byte(`{"name":"a1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	o := New().SetCache(&CacheOptions{})
+	if err := o.AddFile(filepath.Join(dir, "*.json")); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := o.Load("a.json"); err != nil || item.String() != `{"name":"a1"}` {
+		t.Fatalf("Configurator.Load(): %v %v", item, err)
+	}
+
+	events := make(chan Event, 4)
+	w, err := o.Watch(10*time.Millisecond, func(e Event) { events <- e })
+	if err != nil {
+		t.Fatalf("Configurator.Watch(): %s", err)
+	}
+	defer w.Close()
+
+	if err := ioutil.WriteFile(path, []byte(`{"name":"a2"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Configurator.Watch(): timeout")
+	}
+	if item, err := o.Load("a.json"); err != nil || item.String() != `{"name":"a2"}` {
+		t.Fatalf("Configurator.Load(): %v %v", item, err)
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// Change describes a change of the config target.
+type Change struct {
+	// Target is the name of the changed config target.
+	Target string
+
+	// Old is the config item before the change, it is nil if the config target
+	// did not exist or has not been loaded before.
+	Old Item
+
+	// New is the config item after the change, it is nil if the config target
+	// no longer exists.
+	New Item
+
+	// Loader is the loader that produced the new config item, it is nil if the
+	// config target no longer exists.
+	Loader Loader
+}
+
+// ChangeFunc type defines the function that handles the config target changes.
+type ChangeFunc func(*Change)
+
+// OnChange subscribes to the changes of the given config target.
+// The target "*" subscribes to the changes of all config targets. The callbacks
+// of the same target are called serially, and a panic in a callback does not
+// affect the other callbacks.
+func (o *configurator) OnChange(target string, fn ChangeFunc) Configurator {
+	o.Subscribe(target, fn)
+	return o
+}
+
+// The subscriber type is a subscription of the config target changes, the pointer
+// identifies the subscription.
+type subscriber struct {
+	fn ChangeFunc
+}
+
+// Subscribe subscribes to the changes of the given config target like OnChange,
+// and returns the function that cancels the subscription.
+func (o *configurator) Subscribe(target string, fn ChangeFunc) func() {
+	// Remember the current config item, so that the subscribers can get the
+	// old config item when the first change occurs.
+	var item Item
+	if target != "*" {
+		item, _, _ = o.load(target)
+	}
+
+	s := &subscriber{fn}
+	o.mutex.Lock()
+	if o.subscribers == nil {
+		o.subscribers = make(map[string][]*subscriber)
+		o.items = make(map[string]Item)
+	}
+	o.subscribers[target] = append(o.subscribers[target], s)
+	if _, found := o.items[target]; !found && item != nil {
+		o.items[target] = item
+	}
+	o.mutex.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { o.unsubscribe(target, s) })
+	}
+}
+
+// The unsubscribe method removes the given subscriber of the given config target.
+func (o *configurator) unsubscribe(target string, s *subscriber) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	var r []*subscriber
+	for _, x := range o.subscribers[target] {
+		if x != s {
+			r = append(r, x)
+		}
+	}
+	if len(r) > 0 {
+		o.subscribers[target] = r
+		return
+	}
+	// The remembered config item is no longer needed.
+	delete(o.subscribers, target)
+	delete(o.items, target)
+}
+
+// OnError sets the function that handles the errors of the automatic reloading
+// of the watchers created by the Watch method.
+func (o *configurator) OnError(fn func(error)) Configurator {
+	o.mutex.Lock()
+	o.errorHandler = fn
+	o.mutex.Unlock()
+	return o
+}
+
+// The handleError method calls the error handler with the given non-nil error.
+func (o *configurator) handleError(err error) {
+	o.mutex.Lock()
+	fn := o.errorHandler
+	o.mutex.Unlock()
+	if fn != nil && err != nil {
+		fn(err)
+	}
+}
+
+// Reload reloads the given config target and notifies the subscribers if the
+// config target has changed.
+func (o *configurator) Reload(target string) error {
+	lock := o.targetLock(target)
+	lock.Lock()
+	change, fns, err := o.reload(target)
+	if change == nil {
+		lock.Unlock()
+		return err
+	}
+	// The change is queued while holding the lock of the config target, so the
+	// changes are notified in order. The callbacks are called after the lock is
+	// released, a callback that reloads the same config target only queues the
+	// change, which is notified after the callback returns.
+	deliver := lock.push(fns, change)
+	lock.Unlock()
+	if !deliver {
+		return nil
+	}
+	return lock.deliver()
+}
+
+// The reload method reloads the given config target and returns the change and
+// the callbacks to notify. If the config target has not changed, nil is returned.
+func (o *configurator) reload(target string) (*Change, []ChangeFunc, error) {
+	o.Invalidate(target)
+	item, loader, err := o.load(target)
+	if err != nil && err != ErrNotFound {
+		return nil, nil, err
+	}
+	if item != nil {
+		// The config file may be being written, the empty or unparsable config item
+		// is not notified.
+		if item.IsEmpty() {
+			o.Invalidate(target)
+			return nil, nil, ErrEmptyItem
+		}
+		if fi := AsFormatItem(item); lookupFormat(fi.Format()) != nil {
+			if _, err := fi.Tree(); err != nil {
+				o.Invalidate(target)
+				return nil, nil, withTarget(err, target, loader)
+			}
+		}
+	}
+
+	o.mutex.Lock()
+	fns := make([]ChangeFunc, 0, len(o.subscribers[target])+len(o.subscribers["*"]))
+	for _, x := range o.subscribers[target] {
+		fns = append(fns, x.fn)
+	}
+	for _, x := range o.subscribers["*"] {
+		fns = append(fns, x.fn)
+	}
+	if len(fns) == 0 {
+		o.mutex.Unlock()
+		return nil, nil, nil
+	}
+	old := o.items[target]
+	if item == nil {
+		delete(o.items, target)
+	} else {
+		o.items[target] = item
+	}
+	o.mutex.Unlock()
+
+	if old == nil && item == nil {
+		return nil, nil, nil
+	}
+	if old != nil && item != nil && bytes.Equal(old.Bytes(), item.Bytes()) {
+		return nil, nil, nil
+	}
+	return &Change{Target: target, Old: old, New: item, Loader: loader}, fns, nil
+}
+
+// The reloadChanged method reloads the given changed config target, the subscribed
+// config targets with the same name and the subscribed config targets that depend
+// on them.
+// For example: Given "name", reloads "name", "name.json", "name.yaml", etc.
+func (o *configurator) reloadChanged(name string) {
+	// The subscribers of the short names of the config files added by the AddDir
+	// method are also notified.
+	names := targetNames(name)
+	dependents := make(map[string]bool)
+	for _, n := range names {
+		for target := range o.dependentsOf(n) {
+			dependents[target] = true
+		}
+	}
+	targets := []string{name}
+	o.mutex.Lock()
+	for target := range o.subscribers {
+		if target != name && (matchTarget(target, names) || dependents[target]) {
+			targets = append(targets, target)
+		}
+	}
+	o.mutex.Unlock()
+
+	for _, target := range targets {
+		// The subscribers will be notified at the next successful reload.
+		o.handleError(o.Reload(target))
+	}
+}
+
+// The matchTarget function determines whether the given config target is the same
+// as any of the given names.
+func matchTarget(target string, names []string) bool {
+	for _, name := range names {
+		if sameTarget(target, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// The addDependency method records that the given dependent config target depends
+// on the given config target, such as including or referencing it.
+func (o *configurator) addDependency(target, dependent string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.dependents == nil {
+		o.dependents = make(map[string]map[string]bool)
+	}
+	if o.dependents[target] == nil {
+		o.dependents[target] = make(map[string]bool)
+	}
+	o.dependents[target][dependent] = true
+}
+
+// The dependentsOf method returns the config targets that depend directly or
+// indirectly on the config targets with the given name.
+func (o *configurator) dependentsOf(name string) map[string]bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	r := make(map[string]bool)
+	var walk func(string, bool)
+	walk = func(name string, byName bool) {
+		for target, dependents := range o.dependents {
+			if target != name && !(byName && sameTarget(target, name)) {
+				continue
+			}
+			for dependent := range dependents {
+				if !r[dependent] {
+					r[dependent] = true
+					walk(dependent, false)
+				}
+			}
+		}
+	}
+	walk(name, true)
+	return r
+}
+
+// The sameTarget function determines whether the given config target has the given name.
+// For example: Given "name", returns true for "name", "name.json", "name.yaml", etc.
+func sameTarget(target, name string) bool {
+	return target == name || strings.TrimSuffix(target, filepath.Ext(target)) == name
+}
+
+// The targetLock type is the lock of a config target, it also holds the queue of
+// the changes to be notified.
+type targetLock struct {
+	sync.Mutex
+	queue   []notification
+	running bool
+}
+
+// The notification type is a queued change and the callbacks to notify.
+type notification struct {
+	fns    []ChangeFunc
+	change *Change
+}
+
+// The push method queues the given change, it must be called with the lock held.
+// If the returned value is true, the caller must deliver the queued changes.
+func (l *targetLock) push(fns []ChangeFunc, change *Change) bool {
+	l.queue = append(l.queue, notification{fns, change})
+	if l.running {
+		return false
+	}
+	l.running = true
+	return true
+}
+
+// The deliver method notifies the queued changes until the queue is empty, the
+// callbacks are called without holding the lock. The first *PanicError of the
+// callbacks is returned.
+func (l *targetLock) deliver() error {
+	var err error
+	l.Lock()
+	for len(l.queue) > 0 {
+		n := l.queue[0]
+		l.queue = l.queue[1:]
+		l.Unlock()
+		for i, j := 0, len(n.fns); i < j; i++ {
+			if e := notify(n.fns[i], n.change); e != nil && err == nil {
+				err = e
+			}
+		}
+		l.Lock()
+	}
+	l.running = false
+	l.Unlock()
+	return err
+}
+
+// The targetLock method returns the lock of the given config target, it is used
+// to serialize the notifications of the same target.
+func (o *configurator) targetLock(target string) *targetLock {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.locks == nil {
+		o.locks = make(map[string]*targetLock)
+	}
+	lock := o.locks[target]
+	if lock == nil {
+		lock = new(targetLock)
+		o.locks[target] = lock
+	}
+	return lock
+}
+
+// PanicError reports that a change callback panicked.
+type PanicError struct {
+	// Target is the name of the changed config target.
+	Target string
+
+	// Value is the value passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// Error returns the error message.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("configurator: change callback of %q panicked: %v", e.Target, e.Value)
+}
+
+// The notify function calls the given callback, the panic is recovered and
+// returned as a *PanicError.
+func notify(fn ChangeFunc, change *Change) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Target: change.Target, Value: v, Stack: debug.Stack()}
+		}
+	}()
+	fn(change)
+	return nil
+}
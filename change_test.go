@@ -0,0 +1,312 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The mapLoader type is a loader used for testing.
+type mapLoader struct {
+	mutex sync.Mutex
+	data  map[string]string
+	err   error
+}
+
+func (l *mapLoader) set(target, content string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.data == nil {
+		l.data = make(map[string]string)
+	}
+	if content == "" {
+		delete(l.data, target)
+	} else {
+		l.data[target] = content
+	}
+}
+
+func (l *mapLoader) Load(target string) (Item, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.err != nil {
+		return nil, l.err
+	}
+	if s, found := l.data[target]; found {
+		return NewItemFromString(s), nil
+	}
+	return nil, nil
+}
+
+func TestConfigurator_OnChange(t *testing.T) {
+	l := new(mapLoader)
+	l.set("a", "a1")
+
+	var changes, all []*Change
+	o := New().Use(l)
+	o.OnChange("a", func(c *Change) { changes = append(changes, c) })
+	o.OnChange("a", func(*Change) { panic("test") })
+	o.OnChange("*", func(c *Change) { all = append(all, c) })
+
+	if err := o.Reload("a"); err != nil {
+		t.Fatalf("Configurator.Reload(): %s", err)
+	}
+	if len(changes) != 0 || len(all) != 0 {
+		t.Fatalf("Configurator.Reload(): %d %d", len(changes), len(all))
+	}
+
+	// The panic of a callback is reported, and the other callbacks are still called.
+	l.set("a", "a2")
+	var e *PanicError
+	if err := o.Reload("a"); !errors.As(err, &e) || e.Target != "a" || e.Value != "test" || len(e.Stack) == 0 {
+		t.Fatalf("Configurator.Reload(): %v", err)
+	}
+	if len(changes) != 1 || len(all) != 1 {
+		t.Fatalf("Configurator.Reload(): %d %d", len(changes), len(all))
+	}
+	c := changes[0]
+	if c.Target != "a" || c.Old.String() != "a1" || c.New.String() != "a2" || c.Loader != l {
+		t.Fatalf("Configurator.Reload(): %+v", c)
+	}
+
+	l.set("a", "")
+	if err := o.Reload("a"); !errors.As(err, &e) {
+		t.Fatalf("Configurator.Reload(): %v", err)
+	}
+	if len(changes) != 2 || changes[1].New != nil || changes[1].Loader != nil || changes[1].Old.String() != "a2" {
+		t.Fatalf("Configurator.Reload(): %+v", changes[1])
+	}
+
+	l.set("b", "b1")
+	if err := o.Reload("b"); err != nil {
+		t.Fatalf("Configurator.Reload(): %s", err)
+	}
+	if len(changes) != 2 || len(all) != 3 || all[2].Target != "b" || all[2].Old != nil {
+		t.Fatalf("Configurator.Reload(): %d %d", len(changes), len(all))
+	}
+
+	l.err = errors.New("test")
+	if err := o.Reload("a"); err == nil {
+		t.Fatal("Configurator.Reload(): no error")
+	}
+}
+
+func TestConfigurator_ReloadInCallback(t *testing.T) {
+	l := new(mapLoader)
+	l.set("a", "a1")
+
+	var values []string
+	o := New().Use(l)
+	o.OnChange("a", func(c *Change) {
+		values = append(values, c.New.String())
+		if c.New.String() == "a2" {
+			// The callback can reload the same config target, the change is
+			// notified after the callback returns.
+			l.set("a", "a3")
+			if err := o.Reload("a"); err != nil {
+				t.Errorf("Configurator.Reload(): %s", err)
+			}
+			values = append(values, "reloaded")
+		}
+	})
+	l.set("a", "a2")
+	done := make(chan error)
+	go func() { done <- o.Reload("a") }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Configurator.Reload(): %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Configurator.Reload(): deadlock")
+	}
+	if want := []string{"a2", "reloaded", "a3"}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("Configurator.Reload(): %v", values)
+	}
+}
+
+func TestConfigurator_ReloadInvalidItem(t *testing.T) {
+	content := `{"name":"a1"}`
+	l := LoaderFunc(func(string) (Item, error) {
+		return newFormatItem([]byte(content), FormatJSON), nil
+	})
+
+	var changes []*Change
+	o := New().Use(l)
+	o.OnChange("a", func(c *Change) { changes = append(changes, c) })
+
+	// The partly written config item is not notified.
+	content = `{"name":`
+	var e *ConfigError
+	if err := o.Reload("a"); !errors.As(err, &e) || e.Target != "a" {
+		t.Fatalf("Configurator.Reload(): %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("Configurator.Reload(): %+v", changes[0])
+	}
+	content = `{"name":"a2"}`
+	if err := o.Reload("a"); err != nil {
+		t.Fatalf("Configurator.Reload(): %s", err)
+	}
+	if len(changes) != 1 || changes[0].Old.String() != `{"name":"a1"}` || changes[0].New.String() != `{"name":"a2"}` {
+		t.Fatalf("Configurator.Reload(): %+v", changes)
+	}
+}
+
+func TestConfigurator_ReloadEmptyItem(t *testing.T) {
+	l := new(mapLoader)
+	l.set("a", "a1")
+
+	var changes []*Change
+	o := New().Use(l)
+	o.OnChange("a", func(c *Change) { changes = append(changes, c) })
+
+	// The truncated config file is not notified.
+	o.Use(LoaderFunc(func(string) (Item, error) { return NewItemFromString(""), nil }))
+	if err := o.Reload("a"); err != ErrEmptyItem || len(changes) != 0 {
+		t.Fatalf("Configurator.Reload(): %v %d", err, len(changes))
+	}
+}
+
+func TestConfigurator_WatchOnChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.json")
+	if err := ioutil.WriteFile(path, []byte(`{"name":"a1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	o := New()
+	if err := o.AddFile(filepath.Join(dir, "*.json")); err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan *Change, 4)
+	o.OnChange("a.json", func(c *Change) { changes <- c })
+	o.OnChange("a.json", func(*Change) { panic("test") })
+	// The errors of the automatic reloading are reported to the error handler.
+	errs := make(chan error, 4)
+	o.OnError(func(err error) { errs <- err })
+
+	w, err := o.Watch(10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("Configurator.Watch(): %s", err)
+	}
+	defer w.Close()
+
+	if err := ioutil.WriteFile(path, []byte(`{"name":"a2"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-changes:
+		if c.Target != "a.json" || c.Old.String() != `{"name":"a1"}` || c.New.String() != `{"name":"a2"}` {
+			t.Fatalf("Configurator.OnChange(): %+v", c)
+		}
+		if c.Loader == nil {
+			t.Fatal("Configurator.OnChange(): nil loader")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Configurator.OnChange(): timeout")
+	}
+	select {
+	case err := <-errs:
+		var e *PanicError
+		if !errors.As(err, &e) || e.Target != "a.json" {
+			t.Fatalf("Configurator.OnError(): %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Configurator.OnError(): timeout")
+	}
+}
+
+func TestConfigurator_WatchShortName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "foo", "a.json")
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"name":"a1"}`)
+
+	o := New().SetCache(&CacheOptions{})
+	if err := o.AddDir(dir, "*/*.json"); err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		Name string `json:"name"`
+	}
+	if err := o.LoadJSON("a", &v); err != nil || v.Name != "a1" {
+		t.Fatalf("Configurator.LoadJSON(): %v %s", err, v.Name)
+	}
+
+	// The cached config items of the short names are invalidated by rescanning.
+	write(`{"name":"a22"}`)
+	o.Rescan()
+	if err := o.LoadJSON("a", &v); err != nil || v.Name != "a22" {
+		t.Fatalf("Configurator.LoadJSON(): %v %s", err, v.Name)
+	}
+	if item, err := o.Load("a"); err != nil || item.String() != `{"name":"a22"}` {
+		t.Fatalf("Configurator.Load(): %v %v", item, err)
+	}
+
+	// The subscribers of the short names are notified.
+	changes := make(chan *Change, 4)
+	o.OnChange("a", func(c *Change) { changes <- c })
+	o.OnChange("foo/a", func(c *Change) { changes <- c })
+	w, err := o.Watch(10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("Configurator.Watch(): %s", err)
+	}
+	defer w.Close()
+
+	write(`{"name":"a333"}`)
+	targets := make(map[string]bool)
+	for len(targets) < 2 {
+		select {
+		case c := <-changes:
+			if c.New.String() != `{"name":"a333"}` {
+				t.Fatalf("Configurator.OnChange(): %+v", c)
+			}
+			targets[c.Target] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Configurator.OnChange(): timeout %v", targets)
+		}
+	}
+	if !targets["a"] || !targets["foo/a"] {
+		t.Fatalf("Configurator.OnChange(): %v", targets)
+	}
+	if err := o.LoadJSON("a", &v); err != nil || v.Name != "a333" {
+		t.Fatalf("Configurator.LoadJSON(): %v %s", err, v.Name)
+	}
+}
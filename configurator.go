@@ -16,6 +16,9 @@ package configurator
 
 import (
 	"errors"
+	"reflect"
+	"sync"
+	"time"
 )
 
 var (
@@ -26,6 +29,19 @@ var (
 	// ErrNotFound indicates that the configuration target was not found.
 	// When a configuration target cannot be loaded in all loaders, this error will be returned.
 	ErrNotFound = errors.New("configurator: not found")
+
+	// ErrUnknownFormat reports that the format of the config item is unknown.
+	// All UnknownFormatError instances match this error through errors.Is.
+	ErrUnknownFormat = errors.New("configurator: unknown format")
+
+	// ErrMissingValue reports that the value of the given path does not exist in the config tree.
+	ErrMissingValue = errors.New("configurator: missing value")
+
+	// ErrAmbiguousTarget reports that the short name of the config target matches
+	// multiple config files added by the AddDir method, or the config target without
+	// ext name matches multiple config files when the ambiguity error is enabled.
+	// All AmbiguousTargetError instances match this error through errors.Is.
+	ErrAmbiguousTarget = errors.New("configurator: ambiguous target")
 )
 
 // Configurator defines the configuration manager.
@@ -35,10 +51,81 @@ type Configurator interface {
 	Use(Loader) Configurator
 
 	// AddFile adds one or more config files to the current loader.
-	// The given parameter need to comply with the search rules supported
-	// by filepath.Glob.
+	// The given patterns need to comply with the search rules supported by
+	// filepath.Glob, in addition, the "**" segments match zero or more directories,
+	// and the brace alternatives are supported, such as "conf/**/*.{yml,yaml}".
+	// The patterns prefixed with "!" are the exclude patterns, such as
+	// "!**/*.example.yaml".
+	// This method comes from the built-in configuration file loader.
+	AddFile(...string) error
+
+	// AddIgnoreFile reads the given .gitignore-style ignore file, the config files
+	// matched by the rules of the ignore file are ignored.
+	// This method comes from the built-in configuration file loader.
+	AddIgnoreFile(string) error
+
+	// Rescan resolves all the added patterns of the config files again, so the config
+	// files created after adding can be loaded, and the cached config items are purged.
+	// This method comes from the built-in configuration file loader.
+	Rescan()
+
+	// SetAutoRescan sets the interval of the automatic rescanning of the config files.
+	// If the interval is positive, a missed config target triggers rescanning (at most
+	// once in the given interval), and the cached config items of the config files
+	// changed since the last scanning are invalidated. The automatic rescanning is
+	// disabled by default.
+	// This method comes from the built-in configuration file loader.
+	SetAutoRescan(time.Duration) Configurator
+
+	// RemoveFile removes the given pattern added by the AddFile or AddDir method, and
+	// purges the cached config items. If the given pattern has not been added, false
+	// is returned.
+	// This method comes from the built-in configuration file loader.
+	RemoveFile(string) bool
+
+	// Reset removes all the added patterns, exclude patterns and ignore rules of the
+	// config files, and purges the cached config items. The custom loaders and the
+	// default config items are kept.
+	// This method comes from the built-in configuration file loader.
+	Reset()
+
+	// Files returns all the registered config files sorted by config target.
+	// This method comes from the built-in configuration file loader.
+	Files() []FileEntry
+
+	// Targets returns the unique names of the registered config targets in order.
+	// This method comes from the built-in configuration file loader.
+	Targets() []string
+
+	// SetFormatOrder sets the format preference order of the config files of the same
+	// config target, such as "yaml", "toml" and "json", the config file of the
+	// preferred format is loaded by the config target without ext name.
+	// This method comes from the built-in configuration file loader.
+	SetFormatOrder(...string) Configurator
+
+	// SetAmbiguityError sets whether to return an error matching ErrAmbiguousTarget
+	// when a config target without ext name matches multiple config files that are
+	// not distinguished by the format preference order.
 	// This method comes from the built-in configuration file loader.
-	AddFile(string) error
+	SetAmbiguityError(bool) Configurator
+
+	// Candidates returns the config files matched by the given config target in
+	// priority order, the first one is loaded and the others are shadowed.
+	// This method comes from the built-in configuration file loader.
+	Candidates(string) []FileEntry
+
+	// AddDir adds the config files that match the given pattern under the given root
+	// directory, the config targets are the slash-separated relative paths without
+	// the ext names, such as "foo/a" for "root/foo/a.json". The short names, such as
+	// "a", can also be used if they are unambiguous, otherwise an error matching
+	// ErrAmbiguousTarget is returned.
+	// This method comes from the built-in configuration file loader.
+	AddDir(string, string) error
+
+	// Watch watches the config files added by the AddFile method.
+	// The given function is called for every detected change, it can be nil.
+	// This method comes from the built-in configuration file loader.
+	Watch(time.Duration, WatchFunc) (Watcher, error)
 
 	// Load loads the given config target.
 	// If the given config target does not exist, ErrNotFound is returned.
@@ -48,6 +135,8 @@ type Configurator interface {
 	Load(string) (Item, error)
 
 	// LoadJSON loads the given config target and binds it to the given object as json.
+	// For all LoadXXX methods, the zero fields of the given struct are set to the
+	// default values of the "default" struct tags before binding.
 	LoadJSON(string, interface{}) error
 
 	// LoadXML loads the given config target and binds it to the given object as xml.
@@ -58,33 +147,279 @@ type Configurator interface {
 
 	// LoadYAML loads the given config target and binds it to the given object as yaml.
 	LoadYAML(string, interface{}) error
+
+	// LoadInto loads the given config target and binds it to the given object
+	// according to the format of the config target.
+	LoadInto(string, interface{}) error
+
+	// LoadConfig loads the given config target and binds it to the given object by
+	// the format independent decoder, the "config" struct tags are used and the
+	// format-native struct tags are the fallback. See DecodeTree for details.
+	LoadConfig(string, interface{}) error
+
+	// LoadWith loads the given config target and binds it to the given object by the
+	// given function. Like the LoadXXX methods, the default values are set before
+	// binding, the strict mode applies to the config item given to the function, and
+	// the bound object is validated after binding. The decoded value is not cached.
+	LoadWith(string, interface{}, DecodeFunc) error
+
+	// LoadTree loads the given config target and returns the parsed config tree.
+	LoadTree(string) (Tree, error)
+
+	// LoadMerged loads the given config target from all the registered loaders and
+	// deep-merges them by priority order, the merged config item is encoded as json.
+	// If the given merge options is nil, the default options are used.
+	// If no loader can load the config target, ErrNotFound is returned.
+	LoadMerged(string, *MergeOptions) (Item, error)
+
+	// OnChange subscribes to the changes of the given config target.
+	// The target "*" subscribes to the changes of all config targets. The callbacks
+	// of the same target are called serially without holding any lock, so they can
+	// reload the config targets. A panic in a callback does not affect the other
+	// callbacks, it is reported as a *PanicError.
+	OnChange(string, ChangeFunc) Configurator
+
+	// Subscribe subscribes to the changes of the given config target like OnChange,
+	// and returns the function that cancels the subscription.
+	Subscribe(string, ChangeFunc) func()
+
+	// OnError sets the function that handles the errors of the automatic reloading
+	// of the watchers created by the Watch method, such as the loader errors and the
+	// *PanicError of the change callbacks. If the given function is nil, the errors
+	// are ignored.
+	OnError(func(error)) Configurator
+
+	// Reload reloads the given config target and notifies the subscribers if the
+	// config target has changed. The empty or unparsable config item (for example,
+	// a config file being written) is not notified, the error is returned and the
+	// subscribers will be notified at the next successful reload. If a callback
+	// panics, the other callbacks are still called and a *PanicError is returned.
+	// The watchers created by the Watch method reload the changed config targets
+	// automatically.
+	Reload(string) error
+
+	// SetCache enables the cache of the loaded config items and the decoded values.
+	// The cached values are keyed by the config target (and by the format and the
+	// type of the object for the decoded values). The decoded values are shallow
+	// copied into the given objects, so the reference values (maps, slices and
+	// pointers) of the loaded objects must not be modified. If the given options
+	// is nil, the cache is disabled.
+	// The cache entries of the config targets are invalidated automatically when
+	// the config targets are reloaded or the watched config files change.
+	SetCache(*CacheOptions) Configurator
+
+	// Invalidate removes the cache entries of the given config target and the config
+	// targets that depend on it (including or referencing it).
+	Invalidate(string)
+
+	// Purge removes all cache entries.
+	Purge()
+
+	// SetInterpolation enables or disables the variable interpolation.
+	// If enabled, the string values of the loaded config items are expanded:
+	//
+	//     ${NAME}               The value of the environment variable NAME.
+	//     ${NAME:-default}      The default value is used if NAME is unset or empty.
+	//     ${NAME:?message}      An error is returned if NAME is unset or empty.
+	//     ${env:NAME}           The value of the environment variable NAME.
+	//     ${ref:target#path}    The value of the given path of another config target.
+	//     ${scheme:key}         The value resolved by the resolver of the given scheme.
+	//     $${NAME}              The literal "${NAME}".
+	//
+	// If a string value is exactly one reference, the referenced value keeps its type.
+	// The interpolation operates on the parsed config tree, so it only applies to the
+	// config items with a known format.
+	SetInterpolation(bool) Configurator
+
+	// SetResolver registers the variable resolver of the given scheme.
+	// The built-in "env" and "ref" schemes can not be replaced.
+	SetResolver(string, Resolver) Configurator
+
+	// SetSchema attaches the given schema to the given config target.
+	// The Load method and all LoadXXX methods validate the parsed config tree of the
+	// config target against the schema, regardless of the source format. The schema
+	// attached to the name of the config target is used if there is no schema attached
+	// to the config target, for example, the schema of "name" is used for "name.json".
+	// If the given schema is nil, the schema of the config target is removed.
+	SetSchema(string, Schema) Configurator
+
+	// SetValidator sets the validator of the bound config objects.
+	// The LoadXXX methods and the LoadInto method validate the config object after
+	// binding, a *ValidationError is returned by the built-in validator if the config
+	// object is invalid. If the given validator is nil, the validation is disabled.
+	SetValidator(Validator) Configurator
+
+	// SetDefault sets the default config item of the given config target, it has
+	// the lowest priority and is used only if no loader can load the config target.
+	// The default config item of the name of the config target is used if there is
+	// no default config item of the config target, for example, the default config
+	// item of "name" is used for "name.json".
+	// If the given config item is nil, the default config item is removed.
+	SetDefault(string, Item) Configurator
+
+	// SetDecodeOptions sets the options of the format independent decoder used by
+	// the LoadConfig method. If the given options is nil, the default options are used.
+	SetDecodeOptions(*DecodeOptions) Configurator
+
+	// SetStrict enables or disables the strict mode. In the strict mode, the LoadXXX
+	// methods and the LoadInto method fail if the config document contains the keys
+	// that do not map to the bound object, the unknown keys are reported by a
+	// *UnknownKeysError. See the Strict function for details.
+	SetStrict(bool) Configurator
 }
 
 // New creates and returns a new Configurator instance.
 func New() Configurator {
-	fs := newFileLoader()
-	return &configurator{fs: fs, loaders: []Loader{fs}}
+	fs, defaults := newFileLoader(), new(defaultLoader)
+	o := &configurator{fs: fs, defaults: defaults, loaders: []Loader{defaults, fs}}
+	fs.onRescan(o.invalidateChanged)
+	return o
 }
 
 // The configurator type is a built-in implementation of the Configurator interface.
 type configurator struct {
-	fs      *fileLoader
-	loaders []Loader
+	fs       *fileLoader
+	defaults *defaultLoader
+	loaders  []Loader
+
+	mutex         sync.Mutex
+	cache         *cache
+	interpolation bool
+	resolvers     map[string]Resolver
+	subscribers   map[string][]*subscriber
+	items         map[string]Item
+	locks         map[string]*targetLock
+	errorHandler  func(error)
+	dependents    map[string]map[string]bool
+	schemas       map[string]Schema
+	validator     Validator
+	decodeOptions *DecodeOptions
+	strict        bool
 }
 
 // Use registers a custom configuration loader.
 // The last registered configuration loader will have the highest priority.
 func (o *configurator) Use(loader Loader) Configurator {
 	o.loaders = append(o.loaders, loader)
+	if l, ok := loader.(*fileLoader); ok {
+		l.onRescan(o.invalidateChanged)
+	}
 	return o
 }
 
 // AddFile adds one or more config files to the current loader.
-// The given parameter need to comply with the search rules supported
-// by filepath.Glob.
+// The given patterns need to comply with the search rules supported by
+// filepath.Glob, the "**" segments and the brace alternatives are also supported.
+// The patterns prefixed with "!" are the exclude patterns.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) AddFile(patterns ...string) error {
+	return o.fs.AddFile(patterns...)
+}
+
+// AddIgnoreFile reads the given .gitignore-style ignore file, the config files
+// matched by the rules of the ignore file are ignored.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) AddIgnoreFile(name string) error {
+	return o.fs.AddIgnoreFile(name)
+}
+
+// Rescan resolves all the added patterns of the config files again, and purges
+// the cached config items.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) Rescan() {
+	o.fs.Rescan()
+	// The cached config items may come from the deleted config files.
+	o.Purge()
+}
+
+// SetAutoRescan sets the interval of the automatic rescanning of the config files.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) SetAutoRescan(interval time.Duration) Configurator {
+	o.fs.SetAutoRescan(interval)
+	return o
+}
+
+// RemoveFile removes the given pattern added by the AddFile or AddDir method, and
+// purges the cached config items.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) RemoveFile(pattern string) bool {
+	if !o.fs.RemoveFile(pattern) {
+		return false
+	}
+	// The cached config items may come from the removed config files.
+	o.Purge()
+	return true
+}
+
+// Reset removes all the added patterns, exclude patterns and ignore rules of the
+// config files, and purges the cached config items.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) Reset() {
+	o.fs.Reset()
+	// The cached config items may come from the removed config files.
+	o.Purge()
+}
+
+// Files returns all the registered config files sorted by config target.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) Files() []FileEntry {
+	return o.fs.Files()
+}
+
+// Targets returns the unique names of the registered config targets in order.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) Targets() []string {
+	return o.fs.Targets()
+}
+
+// SetFormatOrder sets the format preference order of the config files of the same
+// config target, and purges the cached config items.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) SetFormatOrder(formats ...string) Configurator {
+	o.fs.SetFormatOrder(formats...)
+	// The cached config items may come from the shadowed config files.
+	o.Purge()
+	return o
+}
+
+// SetAmbiguityError sets whether to return an error matching ErrAmbiguousTarget
+// when a config target without ext name matches multiple config files of the same
+// preference, and purges the cached config items.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) SetAmbiguityError(enabled bool) Configurator {
+	o.fs.SetAmbiguityError(enabled)
+	o.Purge()
+	return o
+}
+
+// Candidates returns the config files matched by the given config target in
+// priority order, the first one is loaded and the others are shadowed.
 // This method comes from the built-in configuration file loader.
-func (o *configurator) AddFile(pattern string) error {
-	return o.fs.AddFile(pattern)
+func (o *configurator) Candidates(target string) []FileEntry {
+	return o.fs.Candidates(target)
+}
+
+// AddDir adds the config files that match the given pattern under the given root
+// directory, the config targets are the relative paths.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) AddDir(root, pattern string) error {
+	return o.fs.AddDir(root, pattern)
+}
+
+// Watch watches the config files added by the AddFile method.
+// The given function is called for every detected change, it can be nil.
+// The changed config targets are reloaded automatically and the subscribers
+// are notified after the given function is called.
+// This method comes from the built-in configuration file loader.
+func (o *configurator) Watch(interval time.Duration, fn WatchFunc) (Watcher, error) {
+	// The cache entries of the changed config targets are invalidated when the
+	// config files are rescanned.
+	return o.fs.Watch(interval, func(e Event) {
+		if fn != nil {
+			fn(e)
+		}
+		o.reloadChanged(e.Target)
+	})
 }
 
 // Load loads the given config target.
@@ -93,52 +428,253 @@ func (o *configurator) AddFile(pattern string) error {
 // or all registered config loaders cannot load the config target (the semantic target
 // does not exist), it will be automatically delegated to the built-in config file loader.
 func (o *configurator) Load(target string) (Item, error) {
+	item, _, err := o.load(target)
+	return item, err
+}
+
+// The load method loads the given config target and returns the loader that
+// produced the config item.
+func (o *configurator) load(target string) (Item, Loader, error) {
+	return o.loadChain(target, nil)
+}
+
+// The loadChain method loads and processes the given config target.
+// The given chain is the list of the config targets being processed, it is used
+// to detect the reference cycles.
+func (o *configurator) loadChain(target string, chain []string) (Item, Loader, error) {
+	c := o.getCache()
+	if c != nil {
+		if v, found := c.get(target); found {
+			r := v.(*loadResult)
+			return r.item, r.loader, nil
+		}
+	}
+	item, loader, err := o.loadRaw(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	if item, err = o.process(target, item, append(chain[:len(chain):len(chain)], target)); err != nil {
+		return nil, nil, withTarget(err, target, loader)
+	}
+	if c != nil {
+		c.set(target, target, &loadResult{item, loader})
+	}
+	return item, loader, nil
+}
+
+// The loadRaw method loads the given config target from the registered loaders
+// without any processing.
+func (o *configurator) loadRaw(target string) (Item, Loader, error) {
 	for k := len(o.loaders) - 1; k >= 0; k-- {
 		if item, err := o.loaders[k].Load(target); err == nil {
 			if item != nil {
-				return item, nil
+				return item, o.loaders[k], nil
 			}
 		} else {
 			if err != ErrNotFound {
-				return nil, err
+				// The loader errors are returned unchanged, only the *ConfigError
+				// of the codec failures is given the config target.
+				return nil, nil, withTarget(err, target, o.loaders[k])
 			}
 		}
 	}
-	return nil, ErrNotFound
+	return nil, nil, ErrNotFound
 }
 
-// LoadJSON loads the given config target and binds it to the given object as json.
-func (o *configurator) LoadJSON(target string, v interface{}) error {
-	if item, err := o.Load(target); err != nil {
+// The process method processes the loaded config item, such as including,
+// interpolation and validation.
+func (o *configurator) process(target string, item Item, chain []string) (Item, error) {
+	item, err := o.include(item, chain)
+	if err != nil {
+		return nil, err
+	}
+	if o.interpolationEnabled() {
+		if item, err = o.interpolate(item, chain); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.validate(target, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// The loadResult type is used to cache the loaded config item.
+type loadResult struct {
+	item   Item
+	loader Loader
+}
+
+// The decode method loads the given config target and binds it to the given
+// object by the given function. The default values of the struct tags are set
+// before binding, and the bound object is validated after binding.
+// If the cache is enabled, the decoded value is cached by the given format name
+// and the type of the given object. If the given format name is empty, the decoded
+// value is not cached.
+func (o *configurator) decode(target, format string, v interface{}, f DecodeFunc) error {
+	c := o.getCache()
+	rv := reflect.ValueOf(v)
+	valid := rv.Kind() == reflect.Ptr && !rv.IsNil()
+
+	var key string
+	if c != nil && valid && format != "" {
+		key = target + "\x00" + format + "\x00" + rv.Type().String()
+		if cached, found := c.get(key); found {
+			rv.Elem().Set(reflect.ValueOf(cached).Elem())
+			return nil
+		}
+	}
+	item, loader, err := o.load(target)
+	if err != nil {
 		return err
-	} else {
-		return item.JSON(v)
 	}
+	if valid {
+		if err := setDefaults(rv.Elem(), ""); err != nil {
+			return err
+		}
+	}
+	if o.strictEnabled() {
+		item = Strict(item)
+	}
+	if err := f(item, v); err != nil {
+		if e, ok := err.(*UnknownKeysError); ok {
+			e.Target = target
+		}
+		return withTarget(err, target, loader)
+	}
+	if err := o.check(target, v); err != nil {
+		return err
+	}
+	if key != "" {
+		cached := reflect.New(rv.Type().Elem())
+		cached.Elem().Set(rv.Elem())
+		c.set(target, key, cached.Interface())
+	}
+	return nil
+}
+
+// LoadJSON loads the given config target and binds it to the given object as json.
+func (o *configurator) LoadJSON(target string, v interface{}) error {
+	return o.decode(target, FormatJSON, v, Item.JSON)
 }
 
 // LoadXML loads the given config target and binds it to the given object as xml.
 func (o *configurator) LoadXML(target string, v interface{}) error {
-	if item, err := o.Load(target); err != nil {
-		return err
-	} else {
-		return item.XML(v)
-	}
+	return o.decode(target, FormatXML, v, Item.XML)
 }
 
 // LoadTOML loads the given config target and binds it to the given object as toml.
 func (o *configurator) LoadTOML(target string, v interface{}) error {
-	if item, err := o.Load(target); err != nil {
-		return err
-	} else {
-		return item.TOML(v)
-	}
+	return o.decode(target, FormatTOML, v, Item.TOML)
 }
 
 // LoadYAML loads the given config target and binds it to the given object as yaml.
 func (o *configurator) LoadYAML(target string, v interface{}) error {
-	if item, err := o.Load(target); err != nil {
-		return err
+	return o.decode(target, FormatYAML, v, Item.YAML)
+}
+
+// LoadInto loads the given config target and binds it to the given object
+// according to the format of the config target.
+func (o *configurator) LoadInto(target string, v interface{}) error {
+	// The decoded value is cached by the format name "*", which means the format
+	// of the config item.
+	return o.decode(target, "*", v, decodeItem)
+}
+
+// LoadConfig loads the given config target and binds it to the given object by
+// the format independent decoder.
+func (o *configurator) LoadConfig(target string, v interface{}) error {
+	options := o.getDecodeOptions()
+	if o.strictEnabled() {
+		copied := DecodeOptions{}
+		if options != nil {
+			copied = *options
+		}
+		copied.Strict = true
+		options = &copied
+	}
+	return o.decode(target, ConfigTag, v, func(item Item, v interface{}) error {
+		return DecodeItem(item, v, options)
+	})
+}
+
+// LoadWith loads the given config target and binds it to the given object by the
+// given function, the decoded value is not cached.
+func (o *configurator) LoadWith(target string, v interface{}, f DecodeFunc) error {
+	return o.decode(target, "", v, f)
+}
+
+// LoadTree loads the given config target and returns the parsed config tree.
+func (o *configurator) LoadTree(target string) (Tree, error) {
+	if item, loader, err := o.load(target); err != nil {
+		return nil, err
 	} else {
-		return item.YAML(v)
+		t, err := AsFormatItem(item).Tree()
+		return t, withTarget(err, target, loader)
 	}
 }
+
+// LoadMerged loads the given config target from all the registered loaders and
+// deep-merges them by priority order, the merged config item is encoded as json.
+// If the given merge options is nil, the default options are used.
+// If no loader can load the config target, ErrNotFound is returned.
+func (o *configurator) LoadMerged(target string, options *MergeOptions) (Item, error) {
+	item, err := mergeLoad(o.loaders, target, options)
+	if err != nil {
+		return nil, err
+	}
+	return o.process(target, item, []string{target})
+}
+
+// SetCache enables the cache of the loaded config items and the decoded values.
+// If the given options is nil, the cache is disabled.
+func (o *configurator) SetCache(options *CacheOptions) Configurator {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if options == nil {
+		o.cache = nil
+	} else {
+		o.cache = newCache(*options)
+	}
+	return o
+}
+
+// Invalidate removes the cache entries of the given config target and the config
+// targets that depend on it.
+func (o *configurator) Invalidate(target string) {
+	if c := o.getCache(); c != nil {
+		c.invalidate(target)
+		for dependent := range o.dependentsOf(target) {
+			c.invalidate(dependent)
+		}
+	}
+}
+
+// Purge removes all cache entries.
+func (o *configurator) Purge() {
+	if c := o.getCache(); c != nil {
+		c.purge()
+	}
+}
+
+// The invalidateChanged method removes the cache entries of the config targets
+// of the given changed config file, and the config targets that depend on them.
+func (o *configurator) invalidateChanged(e Event) {
+	if c := o.getCache(); c != nil {
+		// The short names of the config files added by the AddDir method are
+		// also cached separately.
+		for _, name := range targetNames(e.Target) {
+			c.invalidateName(name)
+			for target := range o.dependentsOf(name) {
+				c.invalidate(target)
+			}
+		}
+	}
+}
+
+// The getCache method returns the current cache, nil if the cache is disabled.
+func (o *configurator) getCache() *cache {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.cache
+}
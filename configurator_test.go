@@ -143,6 +143,23 @@ func TestConfigurator(t *testing.T) {
 		if v.Name != "" {
 			t.Fatalf("Configurator.LoadYAML(): %s", v.Name)
 		}
+	}, func(c Configurator) {
+		for _, target := range []string{"test.json", "test.xml", "test.toml", "test.yaml"} {
+			v := new(Value)
+			if err := c.LoadInto(target, v); err != nil {
+				t.Fatalf("Configurator.LoadInto(): %s", err)
+			}
+			if v.Name != "test" {
+				t.Fatalf("Configurator.LoadInto(): %s", v.Name)
+			}
+		}
+
+		if err := c.LoadInto("test.txt", new(Value)); !errors.Is(err, ErrUnknownFormat) {
+			t.Fatalf("Configurator.LoadInto(): %v", err)
+		}
+		if err := c.LoadInto("unknown", new(Value)); err != ErrNotFound {
+			t.Fatalf("Configurator.LoadInto(): %v", err)
+		}
 	})
 }
 
@@ -0,0 +1,440 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigTag is the struct tag name used by the format independent decoder.
+const ConfigTag = "config"
+
+// DecodeOptions defines the options of the format independent decoder.
+type DecodeOptions struct {
+	// Tag is the struct tag name, the default is "config".
+	// The format-native struct tag, such as "json" for json documents, is used if
+	// the struct field does not have the tag.
+	Tag string
+
+	// IgnoreCase indicates whether the config keys are matched case-insensitively.
+	// The struct fields without tags are always matched case-insensitively.
+	IgnoreCase bool
+
+	// SnakeCase indicates whether the config keys are matched after removing the
+	// underscores and hyphens case-insensitively, so that "max_conns", "max-conns"
+	// and "maxConns" all match the struct field MaxConns.
+	SnakeCase bool
+
+	// Hooks are the custom decode hooks, they are used before the built-in decode
+	// hooks in order, the first hook that supports the target type wins.
+	Hooks []DecodeHook
+
+	// Strict indicates whether the config keys that do not map to the bound object
+	// are rejected, all unknown keys are reported by a *UnknownKeysError.
+	Strict bool
+}
+
+// DecodeTree binds the given config tree to the given object by the struct tags.
+// The struct tags look like `config:"name,omitempty,squash"`, the name is the config
+// key ("-" skips the field), the "omitempty" option is accepted for compatibility
+// with the encoding tags, and the "squash" option decodes the fields of the nested
+// struct from the same level (the embedded structs without names are squashed by
+// default). The "inline" option of the yaml tags is the same as "squash".
+//
+// The string values are converted by the decode hooks uniformly across all formats,
+// the built-in decode hooks support time.Duration, time.Time, ByteSize, net.IP,
+// net.IPNet, url.URL, regexp.Regexp, big.Int, big.Float and their pointers, and the
+// types implementing the encoding.TextUnmarshaler interface are also supported.
+// The big numbers in json documents should be quoted to keep the precision.
+// If the given options is nil, the default options are used.
+// The conversion failures are reported by a *ValueError.
+func DecodeTree(t Tree, v interface{}, options *DecodeOptions) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("configurator: can not decode into %T", v)
+	}
+	d := &treeDecoder{source: t.Source(), format: t.Format()}
+	if options != nil {
+		d.options = *options
+	}
+	if d.options.Tag == "" {
+		d.options.Tag = ConfigTag
+	}
+	if d.options.Strict {
+		if err := d.checkKeys(t, v); err != nil {
+			return err
+		}
+	}
+	return d.decode(rv.Elem(), t.Map(), t.Path())
+}
+
+// DecodeItem binds the given config item to the given object by the struct tags.
+// See DecodeTree for details.
+func DecodeItem(item Item, v interface{}, options *DecodeOptions) error {
+	t, err := AsFormatItem(item).Tree()
+	if err != nil {
+		return err
+	}
+	return DecodeTree(t, v, options)
+}
+
+// The treeDecoder type binds the config trees to the objects.
+type treeDecoder struct {
+	options DecodeOptions
+	source  string
+	format  string
+}
+
+// The decode method binds the given raw value to the given value.
+func (d *treeDecoder) decode(v reflect.Value, raw interface{}, path string) error {
+	if raw == nil {
+		if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface ||
+			v.Kind() == reflect.Map || v.Kind() == reflect.Slice {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+	if ok, err := d.hook(v, raw, path); ok {
+		return err
+	}
+	if rv := reflect.ValueOf(raw); rv.Type().AssignableTo(v.Type()) && v.Kind() != reflect.Interface {
+		if k := rv.Kind(); k != reflect.Map && k != reflect.Slice {
+			v.Set(rv)
+			return nil
+		}
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return d.scalar(v, raw, path)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(v.Elem(), raw, path)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return d.error(v, raw, path, errUnsupportedType)
+		}
+		v.Set(reflect.ValueOf(copyValue(raw)))
+		return nil
+	case reflect.Struct:
+		m, ok := d.toMap(raw)
+		if !ok {
+			return d.error(v, raw, path, errUnsupportedType)
+		}
+		return d.decodeStruct(v, m, path)
+	case reflect.Map:
+		m, ok := d.toMap(raw)
+		if !ok {
+			return d.error(v, raw, path, errUnsupportedType)
+		}
+		return d.decodeMap(v, m, path)
+	case reflect.Slice, reflect.Array:
+		values, ok := raw.([]interface{})
+		if !ok {
+			if _, isMap := raw.(map[string]interface{}); !isMap {
+				// The scalar values are separated by commas, and the empty xml elements
+				// are decoded as empty strings.
+				ss, err := toStringSlice(raw)
+				if err != nil {
+					return d.error(v, raw, path, err)
+				}
+				values = make([]interface{}, len(ss))
+				for i := range ss {
+					values[i] = ss[i]
+				}
+			} else {
+				// A single element of the xml documents is not an array.
+				values = []interface{}{raw}
+			}
+		}
+		return d.decodeSlice(v, values, path)
+	}
+	return d.scalar(v, raw, path)
+}
+
+// The scalar method converts the given scalar value and sets it to the given value.
+func (d *treeDecoder) scalar(v reflect.Value, raw interface{}, path string) error {
+	if err := setValue(v, raw); err != nil {
+		return d.error(v, raw, path, err)
+	}
+	return nil
+}
+
+// The error method creates and returns a *ValueError.
+func (d *treeDecoder) error(v reflect.Value, raw interface{}, path string, err error) error {
+	var e *ValueError
+	if errors.As(err, &e) {
+		return err
+	}
+	return &ValueError{Path: path, Source: d.source, Type: v.Type().String(), Value: raw, Err: err}
+}
+
+// The toMap method converts the given raw value to a map.
+// The empty strings are converted to empty maps, since the empty xml elements are
+// decoded as empty strings.
+func (d *treeDecoder) toMap(raw interface{}) (map[string]interface{}, bool) {
+	switch o := raw.(type) {
+	case map[string]interface{}:
+		return o, true
+	case string:
+		if o == "" {
+			return map[string]interface{}{}, true
+		}
+	}
+	return nil, false
+}
+
+// The decodeSlice method binds the given values to the given slice or array.
+func (d *treeDecoder) decodeSlice(v reflect.Value, values []interface{}, path string) error {
+	if v.Kind() == reflect.Array {
+		if len(values) > v.Len() {
+			return d.error(v, values, path, fmt.Errorf("too many elements"))
+		}
+		for i := range values {
+			if err := d.decode(v.Index(i), values[i], joinIndex(path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	s := reflect.MakeSlice(v.Type(), len(values), len(values))
+	for i := range values {
+		if err := d.decode(s.Index(i), values[i], joinIndex(path, i)); err != nil {
+			return err
+		}
+	}
+	v.Set(s)
+	return nil
+}
+
+// The decodeMap method binds the given map to the given map value.
+func (d *treeDecoder) decodeMap(v reflect.Value, m map[string]interface{}, path string) error {
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, len(m)))
+	}
+	for _, k := range sortedKeys(m) {
+		p := joinKey(path, k)
+		key := reflect.New(t.Key()).Elem()
+		if err := d.scalar(key, k, p); err != nil {
+			return err
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if old := v.MapIndex(key); old.IsValid() {
+			elem.Set(old)
+		}
+		if err := d.decode(elem, m[k], p); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+// The decodeStruct method binds the given map to the given struct value.
+func (d *treeDecoder) decodeStruct(v reflect.Value, m map[string]interface{}, path string) error {
+	keys := sortedKeys(m)
+	for _, f := range d.fields(v.Type()) {
+		k, found := d.match(f, m, keys)
+		if !found {
+			continue
+		}
+		fv, err := fieldByIndex(v, f.index)
+		if err != nil {
+			return d.error(v, m, path, err)
+		}
+		if err := d.decode(fv, m[k], joinKey(path, k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The structField type describes the decoded struct field.
+type structField struct {
+	name   string
+	index  []int
+	tagged bool
+}
+
+// The fields method returns the decoded fields of the given struct type.
+// The fields of the squashed structs are flattened.
+func (d *treeDecoder) fields(t reflect.Type) []structField {
+	var fields []structField
+	for i, n := 0, t.NumField(); i < n; i++ {
+		f := t.Field(i)
+		name, squash, tagged := d.parseTag(f)
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if (squash || (f.Anonymous && !tagged)) && ft.Kind() == reflect.Struct {
+			if f.PkgPath != "" && f.Type.Kind() == reflect.Ptr {
+				// The unexported embedded struct pointers can not be allocated.
+				continue
+			}
+			for _, sf := range d.fields(ft) {
+				sf.index = append([]int{i}, sf.index...)
+				fields = append(fields, sf)
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			// Ignore the unexported fields.
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, structField{name: name, index: []int{i}, tagged: tagged})
+	}
+	return fields
+}
+
+// The parseTag method parses the struct tag of the given field.
+// The format-native struct tag is used if the field does not have the tag.
+func (d *treeDecoder) parseTag(f reflect.StructField) (name string, squash, tagged bool) {
+	tag, found := f.Tag.Lookup(d.options.Tag)
+	if !found && d.format != "" {
+		tag, found = f.Tag.Lookup(d.format)
+	}
+	if !found {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		switch strings.TrimSpace(option) {
+		case "squash", "inline":
+			squash = true
+		case "chardata":
+			// The text of the xml elements.
+			return "#text", false, true
+		}
+	}
+	name = strings.TrimSpace(parts[0])
+	if d.format == FormatXML {
+		// Only the local names of the xml elements are used.
+		if i := strings.LastIndexAny(name, " >"); i >= 0 {
+			name = name[i+1:]
+		}
+	}
+	return name, squash, name != ""
+}
+
+// The match method finds the config key of the given struct field.
+func (d *treeDecoder) match(f structField, m map[string]interface{}, keys []string) (string, bool) {
+	if _, found := m[f.name]; found {
+		return f.name, true
+	}
+	if d.options.IgnoreCase || !f.tagged {
+		for _, k := range keys {
+			if strings.EqualFold(k, f.name) {
+				return k, true
+			}
+		}
+	}
+	if d.options.SnakeCase {
+		name := foldName(f.name)
+		for _, k := range keys {
+			if foldName(k) == name {
+				return k, true
+			}
+		}
+	}
+	return "", false
+}
+
+// The foldName function removes the underscores and hyphens of the given name and
+// converts it to lower case.
+func foldName(s string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+}
+
+// The fieldByIndex function returns the nested field of the given struct value,
+// the nil embedded struct pointers are allocated.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					if !v.CanSet() {
+						return v, errors.New("can not set embedded pointer")
+					}
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// The sortedKeys function returns the sorted keys of the given map.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// The joinKey function joins the given path and key.
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// The joinIndex function joins the given path and array index.
+func joinIndex(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// SetDecodeOptions sets the options of the format independent decoder used by
+// the LoadConfig method.
+func (o *configurator) SetDecodeOptions(options *DecodeOptions) Configurator {
+	o.mutex.Lock()
+	if options == nil {
+		o.decodeOptions = nil
+	} else {
+		copied := *options
+		o.decodeOptions = &copied
+	}
+	o.mutex.Unlock()
+	// The cached config objects have been decoded by the previous options.
+	o.Purge()
+	return o
+}
+
+// The getDecodeOptions method returns the options of the format independent decoder.
+func (o *configurator) getDecodeOptions() *DecodeOptions {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.decodeOptions
+}
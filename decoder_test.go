@@ -0,0 +1,208 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testDecodeLevel int
+
+func (l *testDecodeLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 1
+	case "info":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type testDecodeCommon struct {
+	Name  string `config:"name"`
+	Debug bool   `config:"debug"`
+}
+
+type testDecodeServer struct {
+	Common  testDecodeCommon `config:",squash"`
+	Port    int              `config:"port"`
+	Hosts   []string         `config:"hosts"`
+	Timeout time.Duration    `config:"timeout"`
+	DB      *struct {
+		Host     string `config:"host"`
+		MaxConns int    `config:"max_conns,omitempty"`
+	} `config:"db"`
+	Labels  map[string]string `config:"labels"`
+	Users   []struct{ Name string }
+	Level   testDecodeLevel     `config:"level"`
+	Ignored string              `config:"-"`
+	Extra   map[string][]string `config:"extra"`
+}
+
+func TestDecodeItem(t *testing.T) {
+	var want testDecodeServer
+	for _, ext := range []string{"json", "yaml", "toml", "xml"} {
+		item, err := NewFileItem("test/decode/server." + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v := testDecodeServer{Ignored: "ignored"}
+		if err := DecodeItem(item, &v, nil); err != nil {
+			t.Fatalf("DecodeItem(): %s %s", ext, err)
+		}
+		if v.Common.Name != "api" || !v.Common.Debug || v.Port != 8080 || v.Timeout != 5*time.Second ||
+			v.DB == nil || v.DB.Host != "db1" || v.DB.MaxConns != 10 || v.Labels["zone"] != "east" ||
+			len(v.Users) != 2 || v.Users[1].Name != "bob" || v.Level != 2 || v.Ignored != "ignored" ||
+			!reflect.DeepEqual(v.Hosts, []string{"a", "b"}) {
+			t.Fatalf("DecodeItem(): %s %+v", ext, v)
+		}
+		if ext == "json" {
+			want = v
+		} else if !reflect.DeepEqual(v, want) {
+			t.Fatalf("DecodeItem(): %s %+v", ext, v)
+		}
+	}
+
+	var m map[string]interface{}
+	if err := DecodeItem(NewItemFromString(`{"a": [1, {"b": 2}]}`), &m, nil); err != nil {
+		t.Fatalf("DecodeItem(): %s", err)
+	}
+	if !reflect.DeepEqual(m, map[string]interface{}{"a": []interface{}{float64(1), map[string]interface{}{"b": float64(2)}}}) {
+		t.Fatalf("DecodeItem(): %v", m)
+	}
+	if err := DecodeItem(NewItemFromString(""), &m, nil); err != ErrEmptyItem {
+		t.Fatalf("DecodeItem(): %v", err)
+	}
+}
+
+func TestDecodeTree_NativeTags(t *testing.T) {
+	var v struct {
+		Name  string `json:"app_name" yaml:"appName"`
+		Port  int    `config:"port" json:"http_port"`
+		Inner struct {
+			Value int `yaml:"value"`
+		} `yaml:",inline"`
+	}
+	tree, _ := AsFormatItem(NewItemFromString(`{"app_name": "a", "appName": "b", "port": 1, "http_port": 2}`)).Tree()
+	if err := DecodeTree(tree, &v, nil); err != nil || v.Name != "a" || v.Port != 1 {
+		t.Fatalf("DecodeTree(): %v %+v", err, v)
+	}
+	tree, _ = AsFormatItem(NewItemFromString("appName: c\nport: 3\nvalue: 4\n")).Tree()
+	if err := DecodeTree(tree, &v, nil); err != nil || v.Name != "c" || v.Port != 3 || v.Inner.Value != 4 {
+		t.Fatalf("DecodeTree(): %v %+v", err, v)
+	}
+	tree, _ = AsFormatItem(NewItemFromString(`<app><value>5</value>text</app>`)).Tree()
+	var x struct {
+		Value int    `xml:"a>value"`
+		Text  string `xml:",chardata"`
+	}
+	if err := DecodeTree(tree, &x, nil); err != nil || x.Value != 5 || x.Text != "text" {
+		t.Fatalf("DecodeTree(): %v %+v", err, x)
+	}
+}
+
+func TestDecodeTree_Options(t *testing.T) {
+	tree, _ := AsFormatItem(NewItemFromString(`{"Max_Conns": 1, "MAXIDLE": 2, "idle-timeout": "1s"}`)).Tree()
+	type pool struct {
+		MaxConns    int           `config:"max_conns"`
+		MaxIdle     int           `config:"maxIdle"`
+		IdleTimeout time.Duration `config:"idleTimeout"`
+	}
+	var v pool
+	if err := DecodeTree(tree, &v, nil); err != nil || v != (pool{}) {
+		t.Fatalf("DecodeTree(): %v %+v", err, v)
+	}
+	if err := DecodeTree(tree, &v, &DecodeOptions{IgnoreCase: true}); err != nil || v != (pool{MaxConns: 1, MaxIdle: 2}) {
+		t.Fatalf("DecodeTree(): %v %+v", err, v)
+	}
+	v = pool{}
+	if err := DecodeTree(tree, &v, &DecodeOptions{SnakeCase: true}); err != nil || v != (pool{1, 2, time.Second}) {
+		t.Fatalf("DecodeTree(): %v %+v", err, v)
+	}
+
+	var w struct {
+		Name string `cfg:"n"`
+	}
+	tree, _ = AsFormatItem(NewItemFromString(`{"n": "a"}`)).Tree()
+	if err := DecodeTree(tree, &w, &DecodeOptions{Tag: "cfg"}); err != nil || w.Name != "a" {
+		t.Fatalf("DecodeTree(): %v %+v", err, w)
+	}
+}
+
+func TestDecodeTree_Error(t *testing.T) {
+	item, err := NewFileItem("test/decode/server.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, _ := AsFormatItem(item).Tree()
+	var v struct {
+		Users []struct {
+			Name int `config:"name"`
+		} `config:"users"`
+	}
+	err = DecodeTree(tree, &v, nil)
+	var e *ValueError
+	if !errors.As(err, &e) || e.Path != "users[0].name" || e.Source != item.Path() || e.Value != "alice" {
+		t.Fatalf("DecodeTree(): %v", err)
+	}
+	if err := DecodeTree(tree, v, nil); err == nil || !strings.Contains(err.Error(), "can not decode") {
+		t.Fatalf("DecodeTree(): %v", err)
+	}
+	var w struct {
+		Level testDecodeLevel `config:"name"`
+	}
+	if err := DecodeTree(tree, &w, nil); !errors.As(err, &e) || e.Path != "name" {
+		t.Fatalf("DecodeTree(): %v", err)
+	}
+}
+
+func TestConfigurator_LoadConfig(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/decode/*"); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{"server.json", "server.yaml", "server.toml", "server.xml"} {
+		var v testDecodeServer
+		if err := o.LoadConfig(target, &v); err != nil || v.Common.Name != "api" || v.DB.MaxConns != 10 {
+			t.Fatalf("Configurator.LoadConfig(): %s %v", target, err)
+		}
+	}
+
+	// The LoadInto method uses the format-native struct tags.
+	var u testDecodeServer
+	if err := o.LoadInto("server.yaml", &u); err != nil || u.Common.Name != "" {
+		t.Fatalf("Configurator.LoadInto(): %v", err)
+	}
+
+	var v struct {
+		DB struct {
+			MaxConns int
+		}
+	}
+	if err := o.LoadConfig("server.yaml", &v); err != nil || v.DB.MaxConns != 0 {
+		t.Fatalf("Configurator.LoadConfig(): %v", err)
+	}
+	o.SetDecodeOptions(&DecodeOptions{SnakeCase: true})
+	if err := o.LoadConfig("server.yaml", &v); err != nil || v.DB.MaxConns != 10 {
+		t.Fatalf("Configurator.LoadConfig(): %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"encoding"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultTag is the struct tag name of the default values.
+const DefaultTag = "default"
+
+// SetDefaults sets the default values of the zero fields of the given struct pointer
+// by the "default" struct tags, such as `default:"8080"`. The nested structs are
+// handled recursively. The default values of the slices are separated by commas,
+// and the types supported by the decode hooks (see DecodeTree) are supported.
+func SetDefaults(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("configurator: can not set defaults of %T", v)
+	}
+	return setDefaults(rv.Elem(), "")
+}
+
+// The setDefaults function sets the default values of the given struct value.
+func setDefaults(v reflect.Value, path string) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i, n := 0, t.NumField(); i < n; i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			// Ignore the unexported fields.
+			continue
+		}
+		p := f.Name
+		if f.Anonymous {
+			p = path
+		} else if path != "" {
+			p = path + "." + f.Name
+		}
+		fv := v.Field(i)
+		if s, found := f.Tag.Lookup(DefaultTag); found && fv.IsZero() {
+			// The default values are converted by the built-in decode hooks.
+			if err := new(treeDecoder).decode(fv, s, p); err != nil {
+				if e, ok := err.(*ValueError); ok {
+					err = e.Err
+				}
+				return fmt.Errorf("configurator: invalid default value %q of %s: %s", s, p, err)
+			}
+			continue
+		}
+		if err := setDefaults(fv, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The textUnmarshalerType is the reflect type of the encoding.TextUnmarshaler.
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// The setValue function converts the given scalar value and sets it to the given
+// settable value. The pointers are allocated as needed.
+// The slices and the types supported by the decode hooks are handled by the treeDecoder.
+func setValue(v reflect.Value, raw interface{}) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), raw)
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		s, err := toString(raw)
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		s, err := toString(raw)
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := toBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt64(raw)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(n) {
+			return strconv.ErrRange
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toInt64(raw)
+		if err != nil {
+			return err
+		}
+		if n < 0 || v.OverflowUint(uint64(n)) {
+			return strconv.ErrRange
+		}
+		v.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat64(raw)
+		if err != nil {
+			return err
+		}
+		if v.OverflowFloat(f) {
+			return strconv.ErrRange
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return errUnsupportedType
+		}
+		v.Set(reflect.ValueOf(raw))
+	default:
+		return errUnsupportedType
+	}
+	return nil
+}
+
+// The defaultLoader type is the lowest priority loader of the default config items.
+type defaultLoader struct {
+	mutex sync.RWMutex
+	items map[string]Item
+}
+
+// The set method sets the default config item of the given config target.
+// If the given config item is nil, the default config item is removed.
+func (l *defaultLoader) set(target string, item Item) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if item == nil {
+		delete(l.items, target)
+		return
+	}
+	if l.items == nil {
+		l.items = make(map[string]Item)
+	}
+	l.items[target] = item
+}
+
+// Load loads the default config item of the given config target.
+// The default config item of the name of the config target is used if there is
+// no default config item of the config target, for example, the default config item
+// of "name" is used for "name.json".
+func (l *defaultLoader) Load(target string) (Item, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if item := l.items[target]; item != nil {
+		return item, nil
+	}
+	if item := l.items[strings.TrimSuffix(target, filepath.Ext(target))]; item != nil {
+		return item, nil
+	}
+	return nil, ErrNotFound
+}
+
+// SetDefault sets the default config item of the given config target.
+// If the given config item is nil, the default config item is removed.
+func (o *configurator) SetDefault(target string, item Item) Configurator {
+	o.defaults.set(target, item)
+	// The cached config items may come from the previous default config item.
+	o.Purge()
+	return o
+}
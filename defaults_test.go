@@ -0,0 +1,144 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testDefaultsDB struct {
+	Host string `json:"host" yaml:"host" default:"localhost"`
+	Port uint16 `json:"port" yaml:"port" default:"3306"`
+}
+
+type testDefaultsServer struct {
+	Name    string         `json:"name" yaml:"name" default:"server"`
+	Port    int            `json:"port" yaml:"port" default:"8080"`
+	Debug   bool           `json:"debug" yaml:"debug" default:"true"`
+	Ratio   float64        `json:"ratio" yaml:"ratio" default:"0.5"`
+	Timeout time.Duration  `json:"timeout" yaml:"timeout" default:"5s"`
+	Hosts   []string       `json:"hosts" yaml:"hosts" default:"a,b"`
+	Ports   []int          `json:"ports" yaml:"ports" default:"80,443"`
+	IP      net.IP         `json:"ip" yaml:"ip" default:"127.0.0.1"`
+	Limit   *int           `json:"limit" yaml:"limit" default:"10"`
+	DB      testDefaultsDB `json:"db" yaml:"db"`
+}
+
+func TestSetDefaults(t *testing.T) {
+	v := testDefaultsServer{Port: 80}
+	if err := SetDefaults(&v); err != nil {
+		t.Fatalf("SetDefaults(): %s", err)
+	}
+	limit := 10
+	want := testDefaultsServer{
+		Name:    "server",
+		Port:    80,
+		Debug:   true,
+		Ratio:   0.5,
+		Timeout: 5 * time.Second,
+		Hosts:   []string{"a", "b"},
+		Ports:   []int{80, 443},
+		IP:      net.ParseIP("127.0.0.1"),
+		Limit:   &limit,
+		DB:      testDefaultsDB{Host: "localhost", Port: 3306},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("SetDefaults(): %+v", v)
+	}
+
+	if err := SetDefaults(v); err == nil {
+		t.Fatal("SetDefaults(): no error")
+	}
+	items := []interface{}{
+		&struct {
+			V uint8 `default:"256"`
+		}{},
+		&struct {
+			V int `default:"a"`
+		}{},
+		&struct {
+			V map[string]string `default:"a"`
+		}{},
+	}
+	for _, item := range items {
+		if err := SetDefaults(item); err == nil || !strings.Contains(err.Error(), "invalid default value") {
+			t.Fatalf("SetDefaults(): %v", err)
+		}
+	}
+}
+
+func TestConfigurator_LoadWithDefaults(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/defaults/*"); err != nil {
+		t.Fatal(err)
+	}
+	for _, cache := range []bool{false, true} {
+		if cache {
+			o.SetCache(&CacheOptions{})
+		}
+		var v testDefaultsServer
+		if err := o.LoadYAML("server", &v); err != nil {
+			t.Fatalf("Configurator.LoadYAML(): %s", err)
+		}
+		if v.Name != "api" || v.Port != 8080 || v.DB.Host != "db1" || v.DB.Port != 3306 {
+			t.Fatalf("Configurator.LoadYAML(): %+v", v)
+		}
+	}
+}
+
+func TestConfigurator_SetDefault(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/defaults/*"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Load("client"); err != ErrNotFound {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+
+	o.SetDefault("client", NewItemFromString(`{"name": "client"}`))
+	o.SetDefault("server", NewItemFromString(`{"name": "default", "mode": "debug"}`))
+	var v testDefaultsServer
+	if err := o.LoadJSON("client.json", &v); err != nil || v.Name != "client" || v.Port != 8080 {
+		t.Fatalf("Configurator.LoadJSON(): %v %+v", err, v)
+	}
+	// The default config item has the lowest priority.
+	item, err := o.Load("server")
+	if err != nil || !strings.Contains(item.String(), "api") {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	o.Use(LoaderFunc(func(string) (Item, error) { return nil, ErrNotFound }))
+	if item, err := o.Load("client"); err != nil || item.String() != `{"name": "client"}` {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+
+	// The default config items are merged first.
+	item, err = o.LoadMerged("server", nil)
+	if err != nil {
+		t.Fatalf("Configurator.LoadMerged(): %s", err)
+	}
+	tree, _ := AsFormatItem(item).Tree()
+	if tree.GetString("name") != "api" || tree.GetString("db.host") != "db1" || tree.GetString("mode") != "debug" {
+		t.Fatalf("Configurator.LoadMerged(): %s", item)
+	}
+
+	o.SetDefault("client", nil)
+	if _, err := o.Load("client"); err != ErrNotFound {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EnvLoader interface defines the environment variable loader.
+// The environment variable loader builds a nested config document of the target
+// from the environment variables, for example, given the prefix "APP", the target
+// "database" is built from APP_DATABASE_HOST and APP_DATABASE_PORT as
+// {"host": "...", "port": "..."}. All values are strings, the config item binds
+// them as json by the format independent decoder (see DecodeTree), so the strings
+// are converted to the types of the bound object, such as int, bool and time.Duration.
+type EnvLoader interface {
+	Loader
+
+	// SetSeparator sets the separator of the environment variable names.
+	// The default separator is "_". The separator is used between the prefix,
+	// the target and the nested keys.
+	SetSeparator(string) EnvLoader
+
+	// SetKeyMapper sets the function used to map the environment variable name
+	// parts to the config keys. The default key mapper is strings.ToLower.
+	SetKeyMapper(func(string) string) EnvLoader
+}
+
+// NewEnvLoader creates and returns an environment variable loader instance.
+// The given prefix can be empty, the environment variable names are matched
+// case-insensitively.
+func NewEnvLoader(prefix string) EnvLoader {
+	return &envLoader{prefix: prefix, separator: "_", mapper: strings.ToLower}
+}
+
+// The envLoader type is a built-in implementation of the EnvLoader interface.
+type envLoader struct {
+	mutex     sync.RWMutex
+	prefix    string
+	separator string
+	mapper    func(string) string
+}
+
+// SetSeparator sets the separator of the environment variable names.
+func (o *envLoader) SetSeparator(separator string) EnvLoader {
+	o.mutex.Lock()
+	o.separator = separator
+	o.mutex.Unlock()
+	return o
+}
+
+// SetKeyMapper sets the function used to map the environment variable name
+// parts to the config keys.
+func (o *envLoader) SetKeyMapper(mapper func(string) string) EnvLoader {
+	o.mutex.Lock()
+	o.mapper = mapper
+	o.mutex.Unlock()
+	return o
+}
+
+// Load loads the given config target from the environment variables.
+// If there is no environment variable of the given target, nil Item is returned.
+func (o *envLoader) Load(target string) (Item, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	prefix := target + o.separator
+	if o.prefix != "" {
+		prefix = o.prefix + o.separator + prefix
+	}
+
+	env := os.Environ()
+	// Sort the environment variables so that the result is stable.
+	sort.Strings(env)
+
+	var m map[string]interface{}
+	for _, kv := range env {
+		i := strings.IndexByte(kv, '=')
+		if i <= len(prefix) || !strings.EqualFold(kv[:len(prefix)], prefix) {
+			continue
+		}
+		keys := strings.Split(kv[len(prefix):i], o.separator)
+		for k, j := 0, len(keys); k < j; k++ {
+			if o.mapper != nil {
+				keys[k] = o.mapper(keys[k])
+			}
+		}
+		if m == nil {
+			m = make(map[string]interface{})
+		}
+		setNested(m, keys, kv[i+1:])
+	}
+	if m == nil {
+		return nil, nil
+	}
+	data, err := encodeFormat(FormatJSON, m)
+	if err != nil {
+		return nil, err
+	}
+	return &envItem{newFormatItem(data, FormatJSON)}, nil
+}
+
+// The envItem type is the config item of the environment variables.
+// All values are strings, so the config item is bound by the format independent
+// decoder, which converts the strings to the types of the bound object.
+type envItem struct {
+	*bytesItem
+}
+
+// JSON binds the current config item to the given object as json format.
+func (item *envItem) JSON(o interface{}) error {
+	if len(item.data) == 0 {
+		return ErrEmptyItem
+	}
+	return DecodeItem(item.bytesItem, o, nil)
+}
+
+// Decode binds the current config item to the given object according to the
+// format of the current config item.
+func (item *envItem) Decode(o interface{}) error {
+	return item.JSON(o)
+}
+
+// The setNested function sets the given value to the nested map by the given keys.
+// The nested maps take precedence over the leaf values, so a leaf value will be
+// replaced by a nested map, and will not overwrite an existing nested map.
+func setNested(m map[string]interface{}, keys []string, v interface{}) {
+	for i, j := 0, len(keys)-1; i < j; i++ {
+		sub, ok := m[keys[i]].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			m[keys[i]] = sub
+		}
+		m = sub
+	}
+	if _, ok := m[keys[len(keys)-1]].(map[string]interface{}); !ok {
+		m[keys[len(keys)-1]] = v
+	}
+}
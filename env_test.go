@@ -0,0 +1,137 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setenv(t *testing.T, kv map[string]string) func() {
+	for k, v := range kv {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return func() {
+		for k := range kv {
+			_ = os.Unsetenv(k)
+		}
+	}
+}
+
+func TestNewEnvLoader(t *testing.T) {
+	if NewEnvLoader("") == nil {
+		t.Fatal("NewEnvLoader(): nil")
+	}
+}
+
+func TestEnvLoader_Load(t *testing.T) {
+	defer setenv(t, map[string]string{
+		"ZKITS_TEST_DATABASE_HOST":         "localhost",
+		"ZKITS_TEST_DATABASE_PORT":         "3306",
+		"ZKITS_TEST_DATABASE_PRIMARY_NAME": "primary",
+		"ZKITS_TEST_DATABASE_PRIMARY":      "ignored",
+		"ZKITS_TEST__CACHE__MAX_SIZE":      "64MiB",
+	})()
+
+	l := NewEnvLoader("ZKITS_TEST")
+	item, err := l.Load("database")
+	if err != nil {
+		t.Fatalf("EnvLoader.Load(): %s", err)
+	}
+	if item == nil {
+		t.Fatal("EnvLoader.Load(): nil")
+	}
+	o, err := AsFormatItem(item).Tree()
+	if err != nil {
+		t.Fatalf("Item.Tree(): %s", err)
+	}
+	if got := o.GetString("host"); got != "localhost" {
+		t.Fatalf("EnvLoader.Load(): %s", got)
+	}
+	if got := o.GetInt("port"); got != 3306 {
+		t.Fatalf("EnvLoader.Load(): %d", got)
+	}
+	if got := o.GetString("primary.name"); got != "primary" {
+		t.Fatalf("EnvLoader.Load(): %s", got)
+	}
+
+	if item, err := l.Load("unknown"); err != nil || item != nil {
+		t.Fatalf("EnvLoader.Load(): %v %v", item, err)
+	}
+
+	l.SetSeparator("__").SetKeyMapper(strings.ToUpper)
+	item, err = NewEnvLoader("ZKITS").Load("test_cache")
+	if err != nil || item != nil {
+		t.Fatalf("EnvLoader.Load(): %v %v", item, err)
+	}
+	item, err = l.Load("cache")
+	if err != nil || item == nil {
+		t.Fatalf("EnvLoader.Load(): %v %v", item, err)
+	}
+	if o, _ := AsFormatItem(item).Tree(); o.GetString("MAX_SIZE") != "64MiB" {
+		t.Fatalf("EnvLoader.Load(): %s", item)
+	}
+}
+
+func TestEnvLoader_Decode(t *testing.T) {
+	defer setenv(t, map[string]string{
+		"ZKITS_TEST_SERVER_NAME":    "8080",
+		"ZKITS_TEST_SERVER_PORT":    "8080",
+		"ZKITS_TEST_SERVER_DEBUG":   "true",
+		"ZKITS_TEST_SERVER_RATIO":   "0.5",
+		"ZKITS_TEST_SERVER_TIMEOUT": "5s",
+	})()
+
+	var v struct {
+		Name    string        `json:"name"`
+		Port    int           `json:"port"`
+		Debug   bool          `json:"debug"`
+		Ratio   float64       `json:"ratio"`
+		Timeout time.Duration `json:"timeout"`
+	}
+	o := New().Use(NewEnvLoader("ZKITS_TEST"))
+	for _, load := range []func(string, interface{}) error{o.LoadJSON, o.LoadInto} {
+		if err := load("server", &v); err != nil {
+			t.Fatalf("Configurator.LoadJSON(): %s", err)
+		}
+		if v.Name != "8080" || v.Port != 8080 || !v.Debug || v.Ratio != 0.5 || v.Timeout != 5*time.Second {
+			t.Fatalf("Configurator.LoadJSON(): %+v", v)
+		}
+	}
+	var m map[string]interface{}
+	if err := o.LoadJSON("server", &m); err != nil || m["port"] != "8080" {
+		t.Fatalf("Configurator.LoadJSON(): %v %v", m, err)
+	}
+}
+
+func TestConfigurator_EnvLoader(t *testing.T) {
+	defer setenv(t, map[string]string{"ZKITS_TEST_TREE_NAME": "env"})()
+
+	o := New()
+	if err := o.AddFile("test/tree.json"); err != nil {
+		t.Fatal(err)
+	}
+	if tree, err := o.LoadTree("tree"); err != nil || tree.GetString("name") != "tree" {
+		t.Fatalf("Configurator.LoadTree(): %v", err)
+	}
+	o.Use(NewEnvLoader("ZKITS_TEST"))
+	if tree, err := o.LoadTree("tree"); err != nil || tree.GetString("name") != "env" {
+		t.Fatalf("Configurator.LoadTree(): %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigError reports a failure of parsing or decoding the config target, it wraps
+// the underlying error, so the errors.Is and errors.As functions can be used to
+// match the underlying error, such as *json.SyntaxError. The errors returned by
+// the loaders themselves are not wrapped.
+type ConfigError struct {
+	// Target is the name of the config target, it is empty if the config item is
+	// decoded directly.
+	Target string
+
+	// Loader is the loader of the config target, it is nil if the config item is
+	// decoded directly.
+	Loader Loader
+
+	// Path is the config file path, it is empty if the config item does not
+	// come from a config file.
+	Path string
+
+	// Line is the line number (starting from 1) of the failure, it is 0 if the
+	// position of the failure is unknown, or the config file content has been
+	// rewritten by including or interpolation.
+	Line int
+
+	// Column is the column number (starting from 1) of the failure, it is 0 if
+	// the column is unknown.
+	Column int
+
+	// Snippet is the content of the line of the failure.
+	Snippet string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *ConfigError) Error() string {
+	s := "configurator: "
+	if e.Target != "" {
+		s += "config target " + strconv.Quote(e.Target) + ": "
+	}
+	if e.Path != "" {
+		s += e.Path
+		if e.Line > 0 {
+			s += ":" + strconv.Itoa(e.Line)
+			if e.Column > 0 {
+				s += ":" + strconv.Itoa(e.Column)
+			}
+		}
+		s += ": "
+	} else if e.Line > 0 {
+		s += "line " + strconv.Itoa(e.Line) + ": "
+	}
+	s += e.Err.Error()
+	if e.Snippet != "" {
+		s += "\n\t" + e.Snippet
+	}
+	return s
+}
+
+// Unwrap returns the underlying error.
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// The newConfigError function wraps the given codec error of the given config
+// content, the position of the failure is extracted from the error.
+// The nil error, ErrEmptyItem and the structured errors of this package are
+// returned directly.
+func newConfigError(data []byte, err error) error {
+	switch err.(type) {
+	case nil, *ConfigError, *UnknownFormatError, *ValueError, *UnknownKeysError:
+		return err
+	}
+	if err == ErrEmptyItem {
+		return err
+	}
+	e := &ConfigError{Err: err}
+	e.Line, e.Column = errorPosition(data, err)
+	if e.Line > 0 {
+		e.Snippet = lineOf(data, e.Line)
+	}
+	return e
+}
+
+// The linePattern is used to extract the line and column numbers from the error
+// messages, such as "yaml: line 3: ..." and "Near line 3 (last key parsed 'a')".
+var linePattern = regexp.MustCompile(`(?i)\bline (\d+)(?:,? column (\d+))?`)
+
+// The errorPosition function returns the line and column numbers of the given
+// codec error of the given config content.
+func errorPosition(data []byte, err error) (int, int) {
+	msg := err.Error()
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		return offsetPosition(data, e.Offset)
+	case *json.UnmarshalTypeError:
+		return offsetPosition(data, e.Offset)
+	case *xml.SyntaxError:
+		return e.Line, 0
+	case *yaml.TypeError:
+		if len(e.Errors) > 0 {
+			msg = e.Errors[0]
+		}
+	}
+	if m := linePattern.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		column, _ := strconv.Atoi(m[2])
+		return line, column
+	}
+	return 0, 0
+}
+
+// The offsetPosition function converts the given byte offset (after reading the
+// offending byte) to the line and column numbers.
+func offsetPosition(data []byte, offset int64) (int, int) {
+	if offset <= 0 {
+		return 0, 0
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	head := data[:offset-1]
+	return bytes.Count(head, []byte{'\n'}) + 1, len(head) - bytes.LastIndexByte(head, '\n')
+}
+
+// The lineOf function returns the content of the given line of the given config
+// content, the line number starts from 1.
+func lineOf(data []byte, line int) string {
+	lines := bytes.Split(data, []byte{'\n'})
+	if line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(string(lines[line-1]))
+}
+
+// The withTarget function sets the config target and the loader of the given error
+// if it is a *ConfigError without the config target.
+func withTarget(err error, target string, loader Loader) error {
+	if e, ok := err.(*ConfigError); ok && e.Target == "" {
+		e.Target, e.Loader = target, loader
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConfigError(t *testing.T) {
+	items := []struct {
+		name    string
+		line    int
+		column  int
+		snippet string
+	}{
+		{"bad.json", 3, 14, `"port": 80,,`},
+		{"bad.yaml", 3, 0, "mode: debug"},
+		{"bad.toml", 2, 0, "port ="},
+		{"bad.xml", 3, 0, "<port>80</prot>"},
+		{"type.json", 3, 14, `"port": "80"`},
+		{"type.yaml", 2, 0, "port: abc"},
+	}
+	for _, item := range items {
+		fi, err := NewFileItem("test/errors/" + item.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v struct {
+			Name string
+			Port int
+		}
+		err = AsFormatItem(fi).Decode(&v)
+		var e *ConfigError
+		if !errors.As(err, &e) {
+			t.Fatalf("FileItem.Decode(): %s %v", item.name, err)
+		}
+		if e.Path != fi.Path() || e.Line != item.line || e.Column != item.column || e.Snippet != item.snippet {
+			t.Fatalf("FileItem.Decode(): %s %+v", item.name, e)
+		}
+		if e.Target != "" || e.Loader != nil || e.Unwrap() == nil {
+			t.Fatalf("FileItem.Decode(): %s %+v", item.name, e)
+		}
+	}
+
+	fi, _ := NewFileItem("test/errors/bad.json")
+	var se *json.SyntaxError
+	if err := fi.JSON(new(interface{})); !errors.As(err, &se) {
+		t.Fatalf("FileItem.JSON(): %v", err)
+	}
+	if _, err := AsFormatItem(fi).Tree(); !errors.As(err, &se) || !strings.Contains(err.Error(), "bad.json:3:14: invalid character") {
+		t.Fatalf("FileItem.Tree(): %v", err)
+	}
+	var xe *xml.SyntaxError
+	if err := NewItemFromString("<a>\n<b></c>\n</a>").XML(new(struct{})); !errors.As(err, &xe) ||
+		!strings.HasPrefix(err.Error(), "configurator: line 2: ") {
+		t.Fatalf("Item.XML(): %v", err)
+	}
+	if err := NewItemFromString("").YAML(new(interface{})); err != ErrEmptyItem {
+		t.Fatalf("Item.YAML(): %v", err)
+	}
+}
+
+func TestConfigurator_ConfigError(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/errors/*"); err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]interface{}
+	var e *ConfigError
+	if err := o.LoadYAML("bad", &v); !errors.As(err, &e) || e.Target != "bad" || e.Loader == nil || e.Line != 3 {
+		t.Fatalf("Configurator.LoadYAML(): %v", err)
+	}
+	if !strings.HasPrefix(e.Error(), `configurator: config target "bad": `) {
+		t.Fatalf("ConfigError.Error(): %s", e)
+	}
+	if _, err := o.LoadTree("bad.toml"); !errors.As(err, &e) || e.Target != "bad.toml" || e.Line != 2 {
+		t.Fatalf("Configurator.LoadTree(): %v", err)
+	}
+	// The included config target is reported.
+	o.SetDefault("main", NewItemFromString(`{"$include": ["bad.xml"]}`))
+	if _, err := o.Load("main"); !errors.As(err, &e) || e.Target != "bad.xml" || e.Line != 3 {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	// The positions of the rewritten config content are not reported.
+	var s struct{ Port int }
+	o.SetInterpolation(true)
+	if err := o.LoadYAML("rewritten", &s); !errors.As(err, &e) || e.Path == "" || e.Line != 0 || e.Snippet != "" {
+		t.Fatalf("Configurator.LoadYAML(): %v", err)
+	}
+	o.SetInterpolation(false)
+	if err := o.LoadYAML("rewritten", &s); !errors.As(err, &e) || e.Line != 3 || e.Snippet != "port: abc" {
+		t.Fatalf("Configurator.LoadYAML(): %v", err)
+	}
+
+	failed := errors.New("failed")
+	loader := LoaderFunc(func(target string) (Item, error) {
+		if target == "failed" {
+			return nil, failed
+		}
+		return nil, ErrNotFound
+	})
+	o.Use(loader)
+	// The errors of the custom loaders are returned unchanged.
+	if _, err := o.Load("failed"); err != failed {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	if _, err := o.Load("unknown"); err != ErrNotFound {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+}
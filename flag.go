@@ -0,0 +1,254 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UsageTag is the struct tag name of the flag usages used by the DefineFlags function.
+const UsageTag = "usage"
+
+// FlagLoader interface defines the command-line flag loader.
+// The flag loader builds the config document of the given target from the parsed
+// flag set, the dotted flag names are mapped into a nested document, for example,
+// the flags --db.host and --db.port are built as {"db": {"host": "...", "port": ...}}.
+// Only the explicitly set flags are included, so the flag values override only the
+// given keys when the flag loader is merged with the other loaders, and the flag
+// defaults do not override the keys of the config files.
+type FlagLoader interface {
+	Loader
+
+	// SetSeparator sets the separator of the nested flag names.
+	// The default separator is ".".
+	SetSeparator(string) FlagLoader
+}
+
+// NewFlagLoader creates and returns a command-line flag loader instance for the
+// given config target. The given flag set must be parsed before loading, the
+// flag.CommandLine is used if the given flag set is nil.
+func NewFlagLoader(target string, fs *flag.FlagSet) FlagLoader {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	return &flagLoader{target: target, fs: fs, separator: "."}
+}
+
+// The flagLoader type is a built-in implementation of the FlagLoader interface.
+type flagLoader struct {
+	mutex     sync.RWMutex
+	target    string
+	fs        *flag.FlagSet
+	separator string
+}
+
+// SetSeparator sets the separator of the nested flag names.
+func (o *flagLoader) SetSeparator(separator string) FlagLoader {
+	o.mutex.Lock()
+	o.separator = separator
+	o.mutex.Unlock()
+	return o
+}
+
+// Load loads the given config target from the explicitly set flags.
+// If the given config target is not the target of the flag loader, or there is
+// no explicitly set flag, nil Item is returned.
+func (o *flagLoader) Load(target string) (Item, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	if !sameTarget(target, o.target) {
+		return nil, nil
+	}
+	var m map[string]interface{}
+	// The Visit method visits the explicitly set flags in lexicographical order.
+	o.fs.Visit(func(f *flag.Flag) {
+		var v interface{}
+		if g, ok := f.Value.(flag.Getter); ok {
+			v = g.Get()
+		}
+		switch v.(type) {
+		case bool, string, int, int64, uint, uint64, float64:
+		default:
+			// The other values, such as durations, are kept as strings and
+			// converted when decoding.
+			v = f.Value.String()
+		}
+		if m == nil {
+			m = make(map[string]interface{})
+		}
+		keys := []string{f.Name}
+		if o.separator != "" {
+			keys = strings.Split(f.Name, o.separator)
+		}
+		setNested(m, keys, v)
+	})
+	if m == nil {
+		return nil, nil
+	}
+	return NewItemFromValue(FormatJSON, m)
+}
+
+// DefineFlags defines the flags of the given flag set for the fields of the given
+// config struct pointer, the nested struct fields are defined as the dotted flag
+// names, such as "db.host". The flag names are the names of the "config" struct
+// tags or the lowercase field names, the usages are the "usage" struct tags, and
+// the default values are the current field values or the "default" struct tags.
+// The fields of the types supported by the decode hooks and the slices are defined
+// as string flags, they are converted when decoding.
+func DefineFlags(fs *flag.FlagSet, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("configurator: can not define flags of %T", v)
+	}
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	// The default values are set on a copy of the given struct.
+	copied := reflect.New(rv.Elem().Type())
+	copied.Elem().Set(rv.Elem())
+	if err := setDefaults(copied.Elem(), ""); err != nil {
+		return err
+	}
+	d := &treeDecoder{options: DecodeOptions{Tag: ConfigTag}}
+	return d.defineFlags(fs, copied.Elem(), "")
+}
+
+// The defineFlags method defines the flags of the given struct value.
+func (d *treeDecoder) defineFlags(fs *flag.FlagSet, v reflect.Value, prefix string) error {
+	for _, f := range d.fields(v.Type()) {
+		name := f.name
+		if !f.tagged {
+			name = strings.ToLower(name)
+		}
+		name = prefix + name
+		field := v.Type().FieldByIndex(f.index)
+		fv, err := fieldByIndex(v, f.index)
+		if err != nil {
+			return err
+		}
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				fv = reflect.New(fv.Type().Elem()).Elem()
+			} else {
+				fv = fv.Elem()
+			}
+		}
+		usage := field.Tag.Get(UsageTag)
+		if fv.Kind() == reflect.Struct && !d.isLeaf(fv.Type(), "") {
+			if err := d.defineFlags(fs, fv, name+"."); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := defineFlag(fs, fv, name, usage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The defineFlag function defines the flag of the given field value.
+func defineFlag(fs *flag.FlagSet, v reflect.Value, name, usage string) error {
+	if v.Type() == durationType {
+		fs.Duration(name, time.Duration(v.Int()), usage)
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) || isHookType(v.Type()) {
+		fs.Var(&stringFlag{formatFlag(v)}, name, usage)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		fs.Bool(name, v.Bool(), usage)
+	case reflect.String:
+		fs.String(name, v.String(), usage)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fs.Int64(name, v.Int(), usage)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fs.Uint64(name, v.Uint(), usage)
+	case reflect.Float32, reflect.Float64:
+		fs.Float64(name, v.Float(), usage)
+	case reflect.Slice, reflect.Array:
+		fs.Var(&stringFlag{formatFlag(v)}, name, usage)
+	case reflect.Map, reflect.Interface, reflect.Chan, reflect.Func:
+		// The maps and the dynamic values can not be defined as flags.
+	default:
+		return fmt.Errorf("configurator: can not define flag %q of %s", name, v.Type())
+	}
+	return nil
+}
+
+// The isHookType function determines whether the given type is supported by the
+// built-in decode hooks.
+func isHookType(t reflect.Type) bool {
+	switch t {
+	case timeType, byteSizeType, ipType, ipNetType, urlType, regexpType, bigIntType, bigFloatType:
+		return true
+	}
+	return false
+}
+
+// The formatFlag function formats the given value as the default value of the
+// string flag, the slice elements are separated by commas.
+func formatFlag(v reflect.Value) string {
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		if v.Type() != ipType {
+			values := make([]string, v.Len())
+			for i := range values {
+				values[i] = formatFlag(v.Index(i))
+			}
+			return strings.Join(values, ",")
+		}
+	}
+	if v.IsZero() {
+		return ""
+	}
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// The stringFlag type is the flag.Value of the string flags.
+type stringFlag struct {
+	value string
+}
+
+// String returns the flag value.
+func (f *stringFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+// Set sets the flag value.
+func (f *stringFlag) Set(s string) error {
+	f.value = s
+	return nil
+}
+
+// Get returns the flag value.
+func (f *stringFlag) Get() interface{} {
+	return f.value
+}
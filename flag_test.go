@@ -0,0 +1,146 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"flag"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewFlagLoader(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("db.host", "localhost", "")
+	fs.Int("db.port", 3306, "")
+	fs.Bool("debug", false, "")
+	fs.Duration("timeout", time.Second, "")
+	fs.String("name", "", "")
+
+	loader := NewFlagLoader("server", fs)
+	if item, err := loader.Load("server"); err != nil || item != nil {
+		t.Fatalf("FlagLoader.Load(): %v %v", item, err)
+	}
+	err := fs.Parse([]string{"--db.host=db1", "--db.port", "3307", "--debug", "--timeout=5s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item, err := loader.Load("client"); err != nil || item != nil {
+		t.Fatalf("FlagLoader.Load(): %v %v", item, err)
+	}
+	for _, target := range []string{"server", "server.yaml"} {
+		item, err := loader.Load(target)
+		if err != nil {
+			t.Fatalf("FlagLoader.Load(): %s", err)
+		}
+		if s := item.String(); s != `{"db":{"host":"db1","port":3307},"debug":true,"timeout":"5s"}` {
+			t.Fatalf("FlagLoader.Load(): %s", s)
+		}
+	}
+
+	loader.SetSeparator("")
+	if item, err := loader.Load("server"); err != nil || item.String() != `{"db.host":"db1","db.port":3307,"debug":true,"timeout":"5s"}` {
+		t.Fatalf("FlagLoader.Load(): %v %v", item, err)
+	}
+}
+
+type testFlagServer struct {
+	Name    string        `config:"name" usage:"The server name."`
+	Port    int           `config:"port" default:"8080"`
+	Debug   bool          `config:"debug"`
+	Ratio   float64       `config:"ratio"`
+	Timeout time.Duration `config:"timeout" default:"5s"`
+	Hosts   []string      `config:"hosts"`
+	IP      net.IP        `config:"ip"`
+	Size    ByteSize      `config:"size"`
+	Limit   *uint         `config:"limit"`
+	DB      struct {
+		Host string `config:"host" default:"localhost"`
+		Port uint16
+	} `config:"db"`
+	Created time.Time         `config:"created"`
+	Labels  map[string]string `config:"labels"`
+	Ignored string            `config:"-"`
+}
+
+func TestDefineFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	v := testFlagServer{Name: "api", Hosts: []string{"a", "b"}}
+	if err := DefineFlags(fs, &v); err != nil {
+		t.Fatalf("DefineFlags(): %s", err)
+	}
+	// The given struct is not modified.
+	if v.Port != 0 || v.DB.Host != "" {
+		t.Fatalf("DefineFlags(): %+v", v)
+	}
+
+	var names []string
+	defaults := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+		defaults[f.Name] = f.DefValue
+	})
+	want := []string{
+		"created", "db.host", "db.port", "debug", "hosts", "ip", "limit",
+		"name", "port", "ratio", "size", "timeout",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("DefineFlags(): %v", names)
+	}
+	if defaults["name"] != "api" || defaults["port"] != "8080" || defaults["timeout"] != "5s" ||
+		defaults["hosts"] != "a,b" || defaults["db.host"] != "localhost" || defaults["ip"] != "" {
+		t.Fatalf("DefineFlags(): %v", defaults)
+	}
+	if fs.Lookup("name").Usage != "The server name." {
+		t.Fatalf("DefineFlags(): %s", fs.Lookup("name").Usage)
+	}
+
+	err := fs.Parse([]string{
+		"--name=web", "--db.port=3307", "--hosts=c,d", "--ip=10.0.0.1",
+		"--size=1KiB", "--limit=10", "--created=2020-01-02", "--timeout=1m",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := New()
+	o.SetDefault("server", NewItemFromString(`{"port": 80, "debug": true, "db": {"host": "db1"}}`))
+	o.Use(NewFlagLoader("server", fs))
+	item, err := o.LoadMerged("server", nil)
+	if err != nil {
+		t.Fatalf("Configurator.LoadMerged(): %s", err)
+	}
+	var r testFlagServer
+	if err := DecodeItem(item, &r, nil); err != nil {
+		t.Fatalf("DecodeItem(): %s", err)
+	}
+	if r.Name != "web" || r.Port != 80 || !r.Debug || r.DB.Host != "db1" || r.DB.Port != 3307 ||
+		!reflect.DeepEqual(r.Hosts, []string{"c", "d"}) || !r.IP.Equal(net.IPv4(10, 0, 0, 1)) ||
+		r.Size != 1024 || *r.Limit != 10 || r.Created.Year() != 2020 || r.Timeout != time.Minute {
+		t.Fatalf("DecodeItem(): %+v", r)
+	}
+
+	if err := DefineFlags(fs, v); err == nil {
+		t.Fatal("DefineFlags(): no error")
+	}
+	var invalid struct {
+		C complex64
+	}
+	if err := DefineFlags(flag.NewFlagSet("test", flag.ContinueOnError), &invalid); err == nil {
+		t.Fatal("DefineFlags(): no error")
+	}
+}
@@ -0,0 +1,446 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// These constants define the names of the built-in config formats.
+const (
+	FormatJSON = "json"
+	FormatXML  = "xml"
+	FormatTOML = "toml"
+	FormatYAML = "yaml"
+)
+
+// UnknownFormatError reports that the format of the config item is unknown.
+// This error is returned when trying to decode a config item whose format
+// cannot be determined by the file ext name or content sniffing.
+type UnknownFormatError struct {
+	// Format is the unsupported format name, it is empty if the format
+	// cannot be determined at all.
+	Format string
+}
+
+// Error returns the error message.
+func (e *UnknownFormatError) Error() string {
+	if e.Format == "" {
+		return "configurator: unknown format"
+	}
+	return "configurator: unknown format \"" + e.Format + "\""
+}
+
+// Is determines whether the current error matches the given error.
+// All UnknownFormatError instances match ErrUnknownFormat.
+func (e *UnknownFormatError) Is(err error) bool {
+	return err == ErrUnknownFormat
+}
+
+// Unmarshaler defines the function that parses the encoded data and stores
+// the result in the value pointed to by the given object.
+type Unmarshaler func([]byte, interface{}) error
+
+// Marshaler defines the function that returns the encoding of the given object.
+type Marshaler func(interface{}) ([]byte, error)
+
+// Format defines a registered config format.
+type Format struct {
+	// Name is the unique name of the format, such as "json".
+	Name string
+
+	// Extensions are the file ext names bound to the format, such as ".json".
+	Extensions []string
+
+	// Unmarshal is used to decode the config content of the format.
+	Unmarshal Unmarshaler
+
+	// Marshal is used to encode objects as the format, it can be nil if
+	// the format does not support encoding.
+	Marshal Marshaler
+
+	// The sniff is set by the SetFormatSniffer function.
+	sniff Sniffer
+}
+
+// Sniffer defines the function that determines whether the given content is
+// encoded as the format.
+type Sniffer func([]byte) bool
+
+// The copy method returns a copy of the current format.
+func (f *Format) copy() *Format {
+	c := *f
+	c.Extensions = append([]string(nil), f.Extensions...)
+	return &c
+}
+
+// The formatRegistry type is used to hold all registered config formats.
+type formatRegistry struct {
+	mutex sync.RWMutex
+	list  []*Format
+	exts  map[string]*Format
+}
+
+// The formats variable holds all registered config formats.
+var formats = new(formatRegistry)
+
+func init() {
+	RegisterFormat(FormatJSON, []string{".json"}, json.Unmarshal, json.Marshal)
+	RegisterFormat(FormatXML, []string{".xml"}, unmarshalXML, xml.Marshal)
+	RegisterFormat(FormatTOML, []string{".toml"}, toml.Unmarshal, marshalTOML)
+	RegisterFormat(FormatYAML, []string{".yaml", ".yml"}, yaml.Unmarshal, yaml.Marshal)
+}
+
+// RegisterFormat registers a config format.
+// The given ext names will be used by the built-in file loader to determine the
+// format of the config files, they are case-insensitive and must start with ".".
+// If a format with the same name already exists, it will be replaced. If an ext
+// name has been bound to another format, the last registered format wins.
+// This function panics if the given name is empty or the given Unmarshaler is nil.
+func RegisterFormat(name string, extensions []string, u Unmarshaler, m Marshaler) {
+	if name == "" {
+		panic("configurator: empty format name")
+	}
+	if u == nil {
+		panic("configurator: nil format unmarshaler")
+	}
+
+	f := &Format{Name: name, Unmarshal: u, Marshal: m}
+	for i, j := 0, len(extensions); i < j; i++ {
+		f.Extensions = append(f.Extensions, strings.ToLower(extensions[i]))
+	}
+
+	formats.mutex.Lock()
+	defer formats.mutex.Unlock()
+
+	if formats.exts == nil {
+		formats.exts = make(map[string]*Format)
+	}
+	for i, j := 0, len(formats.list); i < j; i++ {
+		if formats.list[i].Name == name {
+			// Remove the ext names bound to the replaced format.
+			for k, v := range formats.exts {
+				if v == formats.list[i] {
+					delete(formats.exts, k)
+				}
+			}
+			formats.list = append(formats.list[:i], formats.list[i+1:]...)
+			break
+		}
+	}
+	formats.list = append(formats.list, f)
+	for i, j := 0, len(f.Extensions); i < j; i++ {
+		formats.exts[f.Extensions[i]] = f
+	}
+}
+
+// SetFormatSniffer sets the sniffer of the registered config format with the given
+// name. The content sniffing tries the custom formats that have sniffers in order of
+// registration after the built-in formats, the custom formats without sniffers are
+// never sniffed. If the given sniffer is nil, the sniffer of the format is removed.
+// If the format does not exist, false is returned.
+// The sniffer is removed if the format is registered again.
+func SetFormatSniffer(name string, sniffer Sniffer) bool {
+	formats.mutex.Lock()
+	defer formats.mutex.Unlock()
+
+	for i, j := 0, len(formats.list); i < j; i++ {
+		if formats.list[i].Name == name {
+			formats.list[i].sniff = sniffer
+			return true
+		}
+	}
+	return false
+}
+
+// LookupFormat returns a copy of the registered config format with the given name.
+// If the format does not exist, nil is returned.
+func LookupFormat(name string) *Format {
+	if f := lookupFormat(name); f != nil {
+		return f.copy()
+	}
+	return nil
+}
+
+// The lookupFormat function returns the registered config format with the given name.
+// The returned format must not be modified.
+func lookupFormat(name string) *Format {
+	formats.mutex.RLock()
+	defer formats.mutex.RUnlock()
+
+	for i, j := 0, len(formats.list); i < j; i++ {
+		if formats.list[i].Name == name {
+			return formats.list[i]
+		}
+	}
+	return nil
+}
+
+// LookupFormatByExt returns a copy of the registered config format bound to the
+// given file ext name. If the format does not exist, nil is returned.
+func LookupFormatByExt(ext string) *Format {
+	formats.mutex.RLock()
+	defer formats.mutex.RUnlock()
+
+	if f := formats.exts[strings.ToLower(ext)]; f != nil {
+		return f.copy()
+	}
+	return nil
+}
+
+// Formats returns the names of all registered config formats in order of registration.
+func Formats() []string {
+	formats.mutex.RLock()
+	defer formats.mutex.RUnlock()
+
+	r := make([]string, len(formats.list))
+	for i, j := 0, len(formats.list); i < j; i++ {
+		r[i] = formats.list[i].Name
+	}
+	return r
+}
+
+// The formatByExt function returns the format name bound to the given file
+// ext name. If the ext name is not supported, an empty string is returned.
+func formatByExt(ext string) string {
+	formats.mutex.RLock()
+	defer formats.mutex.RUnlock()
+
+	if f := formats.exts[strings.ToLower(ext)]; f != nil {
+		return f.Name
+	}
+	return ""
+}
+
+// The marshalTOML function returns the toml encoding of the given object.
+func marshalTOML(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := toml.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// The unmarshalXML function parses the xml encoded data and stores the result
+// in the value pointed to by the given object.
+// Unlike xml.Unmarshal, this function supports *map[string]interface{} and
+// *interface{}, the root element will be decoded as a map, the attributes and the
+// child elements will be decoded as the keys of the map, the child elements with
+// the same name will be decoded as an array, and the text of the leaf elements
+// will be decoded as a string.
+func unmarshalXML(data []byte, v interface{}) error {
+	switch o := v.(type) {
+	case *map[string]interface{}:
+		m, err := decodeXMLMap(data)
+		if err != nil {
+			return err
+		}
+		*o = m
+		return nil
+	case *interface{}:
+		m, err := decodeXMLMap(data)
+		if err != nil {
+			return err
+		}
+		*o = m
+		return nil
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// The decodeXMLMap function decodes the root element of the given xml document as a map.
+func decodeXMLMap(data []byte) (map[string]interface{}, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		t, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, errors.New("configurator: xml root element not found")
+			}
+			return nil, err
+		}
+		if e, ok := t.(xml.StartElement); ok {
+			v, err := decodeXMLElement(d, e)
+			if err != nil {
+				return nil, err
+			}
+			if m, ok := v.(map[string]interface{}); ok {
+				return m, nil
+			}
+			return make(map[string]interface{}), nil
+		}
+	}
+}
+
+// The decodeXMLElement function decodes the given xml element.
+// If the element has no attributes and child elements, the text of the element is
+// returned, otherwise a map is returned.
+func decodeXMLElement(d *xml.Decoder, e xml.StartElement) (interface{}, error) {
+	var m map[string]interface{}
+	add := func(k string, v interface{}) {
+		if m == nil {
+			m = make(map[string]interface{})
+		}
+		switch o := m[k].(type) {
+		case nil:
+			m[k] = v
+		case []interface{}:
+			m[k] = append(o, v)
+		default:
+			m[k] = []interface{}{o, v}
+		}
+	}
+	for _, attr := range e.Attr {
+		add(attr.Name.Local, attr.Value)
+	}
+
+	text := new(bytes.Buffer)
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch o := t.(type) {
+		case xml.StartElement:
+			v, err := decodeXMLElement(d, o)
+			if err != nil {
+				return nil, err
+			}
+			add(o.Name.Local, v)
+		case xml.CharData:
+			text.Write(o)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if m == nil {
+				return s, nil
+			}
+			if s != "" {
+				m["#text"] = s
+			}
+			return m, nil
+		}
+	}
+}
+
+// The encodeFormat function returns the encoding of the given object as the given format.
+func encodeFormat(format string, v interface{}) ([]byte, error) {
+	f := lookupFormat(format)
+	if f == nil {
+		return nil, &UnknownFormatError{Format: format}
+	}
+	if f.Marshal == nil {
+		return nil, errors.New("configurator: format \"" + format + "\" does not support encoding")
+	}
+	return f.Marshal(v)
+}
+
+// The unmarshalJSONNumber function parses the json encoded object, the numbers are
+// decoded as json.Number.
+func unmarshalJSONNumber(data []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// The itemMap function parses the given config item and returns the map of the
+// config tree. The json numbers are decoded as json.Number, so that the integers
+// are not converted to float64 when the map is encoded again.
+func itemMap(item Item) (map[string]interface{}, error) {
+	fi := AsFormatItem(item)
+	t, err := fi.Tree()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Format() == FormatJSON {
+		return unmarshalJSONNumber(fi.Bytes())
+	}
+	return t.Map(), nil
+}
+
+// The sniffFormat function determines the format of the given content.
+// If the format of the content cannot be determined, an empty string is returned.
+func sniffFormat(data []byte) string {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return ""
+	}
+
+	switch data[0] {
+	case '{', '[':
+		if json.Valid(data) {
+			return FormatJSON
+		}
+	case '<':
+		if isXML(data) {
+			return FormatXML
+		}
+		// Content that starts with "<" cannot be any other built-in format.
+		return ""
+	}
+
+	var m map[string]interface{}
+	if _, err := toml.Decode(string(data), &m); err == nil {
+		return FormatTOML
+	}
+	// Since any JSON document is also a YAML document, we must check YAML last.
+	if err := yaml.Unmarshal(data, &m); err == nil {
+		return FormatYAML
+	}
+
+	// Finally, try the custom formats with sniffers in order of registration.
+	// The sniffers are called without holding the lock of the format registry.
+	var sniffers []*Format
+	formats.mutex.RLock()
+	for i, j := 0, len(formats.list); i < j; i++ {
+		switch formats.list[i].Name {
+		case FormatJSON, FormatXML, FormatTOML, FormatYAML:
+		default:
+			if formats.list[i].sniff != nil {
+				sniffers = append(sniffers, formats.list[i])
+			}
+		}
+	}
+	formats.mutex.RUnlock()
+	for i, j := 0, len(sniffers); i < j; i++ {
+		if sniffers[i].sniff(data) {
+			return sniffers[i].Name
+		}
+	}
+	return ""
+}
+
+// The isXML function determines whether the given content is a well-formed
+// xml document.
+func isXML(data []byte) bool {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := d.Token(); err != nil {
+			return err == io.EOF
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// The unmarshalKV function is a custom Unmarshaler used for testing, it parses
+// "key::value" lines into a map.
+func unmarshalKV(data []byte, v interface{}) error {
+	m, ok := v.(*map[string]interface{})
+	if !ok {
+		return errors.New("kv: unsupported object")
+	}
+	*m = make(map[string]interface{})
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		kv := strings.SplitN(line, "::", 2)
+		if len(kv) != 2 {
+			return errors.New("kv: invalid line")
+		}
+		(*m)[kv[0]] = kv[1]
+	}
+	return nil
+}
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat("kv", []string{".KV"}, unmarshalKV, nil)
+
+	f := LookupFormat("kv")
+	if f == nil {
+		t.Fatal("LookupFormat(): nil")
+	}
+	if f.Name != "kv" || len(f.Extensions) != 1 || f.Extensions[0] != ".kv" {
+		t.Fatalf("LookupFormat(): %v", f)
+	}
+	if got := LookupFormatByExt(".Kv"); got == nil || got.Name != "kv" || got == f {
+		t.Fatalf("LookupFormatByExt(): %v", got)
+	}
+	// The returned format is a copy.
+	f.Extensions[0] = ".changed"
+	if got := LookupFormat("kv"); got.Extensions[0] != ".kv" {
+		t.Fatalf("LookupFormat(): %v", got)
+	}
+	if got := LookupFormat("unknown"); got != nil {
+		t.Fatalf("LookupFormat(): %v", got)
+	}
+
+	names := Formats()
+	if len(names) < 5 || names[0] != FormatJSON || names[len(names)-1] != "kv" {
+		t.Fatalf("Formats(): %v", names)
+	}
+
+	item, err := NewFileItem("test/custom/test.kv")
+	if err != nil {
+		t.Fatalf("NewFileItem(): %s", err)
+	}
+	if got := AsFormatItem(item).Format(); got != "kv" {
+		t.Fatalf("FileItem.Format(): %s", got)
+	}
+	m := make(map[string]interface{})
+	if err := AsFormatItem(item).Decode(&m); err != nil {
+		t.Fatalf("FileItem.Decode(): %s", err)
+	}
+	if m["name"] != "custom" {
+		t.Fatalf("FileItem.Decode(): %v", m)
+	}
+	// The custom formats are sniffed only if they have sniffers.
+	if got := AsFormatItem(NewItemFromString("name::custom")).Format(); got != "" {
+		t.Fatalf("Item.Format(): %s", got)
+	}
+	if SetFormatSniffer("unknown", nil) {
+		t.Fatal("SetFormatSniffer(): true")
+	}
+	sniffer := func(data []byte) bool {
+		m := make(map[string]interface{})
+		return unmarshalKV(data, &m) == nil
+	}
+	if !SetFormatSniffer("kv", sniffer) {
+		t.Fatal("SetFormatSniffer(): false")
+	}
+	if got := AsFormatItem(NewItemFromString("name::custom")).Format(); got != "kv" {
+		t.Fatalf("Item.Format(): %s", got)
+	}
+}
+
+func TestRegisterFormatPanic(t *testing.T) {
+	do := func(f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("RegisterFormat(): no panic")
+			}
+		}()
+		f()
+	}
+
+	do(func() { RegisterFormat("", nil, unmarshalKV, nil) })
+	do(func() { RegisterFormat("test", nil, nil, nil) })
+}
+
+func TestNewItemFromValue(t *testing.T) {
+	v := map[string]interface{}{"name": "test"}
+	for _, format := range []string{FormatJSON, FormatTOML, FormatYAML} {
+		item, err := NewItemFromValue(format, v)
+		if err != nil {
+			t.Fatalf("NewItemFromValue(): %s", err)
+		}
+		if got := AsFormatItem(item).Format(); got != format {
+			t.Fatalf("NewItemFromValue(): %s", got)
+		}
+		m := make(map[string]interface{})
+		if err := AsFormatItem(item).Decode(&m); err != nil {
+			t.Fatalf("NewItemFromValue(): %s", err)
+		}
+		if m["name"] != "test" {
+			t.Fatalf("NewItemFromValue(): %v", m)
+		}
+	}
+
+	if _, err := NewItemFromValue("unknown", v); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("NewItemFromValue(): %v", err)
+	}
+	RegisterFormat("kv-readonly", nil, unmarshalKV, nil)
+	if _, err := NewItemFromValue("kv-readonly", v); err == nil {
+		t.Fatal("NewItemFromValue(): no error")
+	}
+}
+
+func TestFileLoaderFormatExt(t *testing.T) {
+	l := NewFileLoader().MustAddFile("test/*")
+
+	item, err := l.Load("test.yml")
+	if err != nil {
+		t.Fatalf("FileLoader.Load(): %s", err)
+	}
+	if item == nil {
+		t.Fatal("FileLoader.Load(): nil")
+	}
+	if got := item.(FileItem).Base(); got != "test.yaml" {
+		t.Fatalf("FileLoader.Load(): %s", got)
+	}
+}
@@ -0,0 +1,649 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Getter interface defines the typed value getters of the config tree.
+// The GetXXX methods return the zero value if the given path does not exist or
+// the value cannot be converted, the GetXXXOr methods return the given default value
+// instead, and the GetXXXE methods return a *ValueError.
+type Getter interface {
+	// GetString returns the string value of the given path.
+	GetString(string) string
+	GetStringOr(string, string) string
+	GetStringE(string) (string, error)
+
+	// GetInt returns the int value of the given path.
+	GetInt(string) int
+	GetIntOr(string, int) int
+	GetIntE(string) (int, error)
+
+	// GetBool returns the bool value of the given path.
+	// The strings "1", "t", "true", "y", "yes", "on" and their opposites are supported.
+	GetBool(string) bool
+	GetBoolOr(string, bool) bool
+	GetBoolE(string) (bool, error)
+
+	// GetFloat returns the float value of the given path.
+	GetFloat(string) float64
+	GetFloatOr(string, float64) float64
+	GetFloatE(string) (float64, error)
+
+	// GetDuration returns the duration value of the given path.
+	// The strings are parsed by time.ParseDuration, and the numbers are treated
+	// as nanoseconds.
+	GetDuration(string) time.Duration
+	GetDurationOr(string, time.Duration) time.Duration
+	GetDurationE(string) (time.Duration, error)
+
+	// GetTime returns the time value of the given path.
+	// The strings in RFC3339, "2006-01-02 15:04:05" and "2006-01-02" layouts are
+	// supported, and the numbers are treated as unix seconds.
+	GetTime(string) time.Time
+	GetTimeOr(string, time.Time) time.Time
+	GetTimeE(string) (time.Time, error)
+
+	// GetByteSize returns the byte size value of the given path.
+	// The strings such as "64MiB", "64MB", "64M" and "64" are supported, the IEC units
+	// (KiB, MiB, ...) and the single letter units (K, M, ...) are multiples of 1024,
+	// the SI units (KB, MB, ...) are multiples of 1000.
+	GetByteSize(string) uint64
+	GetByteSizeOr(string, uint64) uint64
+	GetByteSizeE(string) (uint64, error)
+
+	// GetStringSlice returns the string slice value of the given path.
+	// A single string will be split by commas.
+	GetStringSlice(string) []string
+	GetStringSliceOr(string, []string) []string
+	GetStringSliceE(string) ([]string, error)
+
+	// GetStringMap returns the string map value of the given path.
+	GetStringMap(string) map[string]string
+	GetStringMapOr(string, map[string]string) map[string]string
+	GetStringMapE(string) (map[string]string, error)
+}
+
+// ValueError reports that the value of the config tree cannot be obtained.
+type ValueError struct {
+	// Path is the full path of the value.
+	Path string
+
+	// Source is the config file path, it is empty if the config tree does not
+	// come from a config file.
+	Source string
+
+	// Type is the name of the expected value type.
+	Type string
+
+	// Value is the original value, it is nil if the value does not exist.
+	Value interface{}
+
+	// Err is the underlying error, ErrMissingValue if the value does not exist.
+	Err error
+}
+
+// Error returns the error message.
+func (e *ValueError) Error() string {
+	var s string
+	if e.Err == ErrMissingValue {
+		s = fmt.Sprintf("configurator: missing value %q", e.Path)
+	} else {
+		s = fmt.Sprintf("configurator: cannot convert %q (%v) to %s", e.Path, e.Value, e.Type)
+	}
+	if e.Source != "" {
+		s += " in " + e.Source
+	}
+	if e.Err != ErrMissingValue && e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the underlying error.
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+// The value method returns the converted value of the given path.
+func (t *tree) value(path, typ string, f func(interface{}) (interface{}, error)) (interface{}, error) {
+	v, found := t.Get(path)
+	if !found {
+		return nil, &ValueError{Path: t.join(path), Source: t.source, Type: typ, Err: ErrMissingValue}
+	}
+	r, err := f(v)
+	if err != nil {
+		return nil, &ValueError{Path: t.join(path), Source: t.source, Type: typ, Value: v, Err: err}
+	}
+	return r, nil
+}
+
+// GetString returns the string value of the given path.
+func (t *tree) GetString(path string) string {
+	return t.GetStringOr(path, "")
+}
+
+// GetStringOr returns the string value of the given path or the given default value.
+func (t *tree) GetStringOr(path string, def string) string {
+	if v, err := t.GetStringE(path); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetStringE returns the string value of the given path or a *ValueError.
+func (t *tree) GetStringE(path string) (string, error) {
+	v, err := t.value(path, "string", func(v interface{}) (interface{}, error) { return toString(v) })
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// GetInt returns the int value of the given path.
+func (t *tree) GetInt(path string) int {
+	return t.GetIntOr(path, 0)
+}
+
+// GetIntOr returns the int value of the given path or the given default value.
+func (t *tree) GetIntOr(path string, def int) int {
+	if v, err := t.GetIntE(path); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetIntE returns the int value of the given path or a *ValueError.
+func (t *tree) GetIntE(path string) (int, error) {
+	v, err := t.value(path, "int", func(v interface{}) (interface{}, error) {
+		n, err := toInt64(v)
+		if err == nil && int64(int(n)) != n {
+			return nil, strconv.ErrRange
+		}
+		return int(n), err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.(int), nil
+}
+
+// GetBool returns the bool value of the given path.
+func (t *tree) GetBool(path string) bool {
+	return t.GetBoolOr(path, false)
+}
+
+// GetBoolOr returns the bool value of the given path or the given default value.
+func (t *tree) GetBoolOr(path string, def bool) bool {
+	if v, err := t.GetBoolE(path); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetBoolE returns the bool value of the given path or a *ValueError.
+func (t *tree) GetBoolE(path string) (bool, error) {
+	v, err := t.value(path, "bool", func(v interface{}) (interface{}, error) { return toBool(v) })
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// GetFloat returns the float value of the given path.
+func (t *tree) GetFloat(path string) float64 {
+	return t.GetFloatOr(path, 0)
+}
+
+// GetFloatOr returns the float value of the given path or the given default value.
+func (t *tree) GetFloatOr(path string, def float64) float64 {
+	if v, err := t.GetFloatE(path); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetFloatE returns the float value of the given path or a *ValueError.
+func (t *tree) GetFloatE(path string) (float64, error) {
+	v, err := t.value(path, "float64", func(v interface{}) (interface{}, error) { return toFloat64(v) })
+	if err != nil {
+		return 0, err
+	}
+	return v.(float64), nil
+}
+
+// GetDuration returns the duration value of the given path.
+func (t *tree) GetDuration(path string) time.Duration {
+	return t.GetDurationOr(path, 0)
+}
+
+// GetDurationOr returns the duration value of the given path or the given default value.
+func (t *tree) GetDurationOr(path string, def time.Duration) time.Duration {
+	if v, err := t.GetDurationE(path); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetDurationE returns the duration value of the given path or a *ValueError.
+func (t *tree) GetDurationE(path string) (time.Duration, error) {
+	v, err := t.value(path, "time.Duration", func(v interface{}) (interface{}, error) { return toDuration(v) })
+	if err != nil {
+		return 0, err
+	}
+	return v.(time.Duration), nil
+}
+
+// GetTime returns the time value of the given path.
+func (t *tree) GetTime(path string) time.Time {
+	return t.GetTimeOr(path, time.Time{})
+}
+
+// GetTimeOr returns the time value of the given path or the given default value.
+func (t *tree) GetTimeOr(path string, def time.Time) time.Time {
+	if v, err := t.GetTimeE(path); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetTimeE returns the time value of the given path or a *ValueError.
+func (t *tree) GetTimeE(path string) (time.Time, error) {
+	v, err := t.value(path, "time.Time", func(v interface{}) (interface{}, error) { return toTime(v) })
+	if err != nil {
+		return time.Time{}, err
+	}
+	return v.(time.Time), nil
+}
+
+// GetByteSize returns the byte size value of the given path.
+func (t *tree) GetByteSize(path string) uint64 {
+	return t.GetByteSizeOr(path, 0)
+}
+
+// GetByteSizeOr returns the byte size value of the given path or the given default value.
+func (t *tree) GetByteSizeOr(path string, def uint64) uint64 {
+	if v, err := t.GetByteSizeE(path); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetByteSizeE returns the byte size value of the given path or a *ValueError.
+func (t *tree) GetByteSizeE(path string) (uint64, error) {
+	v, err := t.value(path, "byte size", func(v interface{}) (interface{}, error) { return toByteSize(v) })
+	if err != nil {
+		return 0, err
+	}
+	return v.(uint64), nil
+}
+
+// GetStringSlice returns the string slice value of the given path.
+func (t *tree) GetStringSlice(path string) []string {
+	return t.GetStringSliceOr(path, nil)
+}
+
+// GetStringSliceOr returns the string slice value of the given path or the given default value.
+func (t *tree) GetStringSliceOr(path string, def []string) []string {
+	if v, err := t.GetStringSliceE(path); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetStringSliceE returns the string slice value of the given path or a *ValueError.
+func (t *tree) GetStringSliceE(path string) ([]string, error) {
+	v, err := t.value(path, "[]string", func(v interface{}) (interface{}, error) { return toStringSlice(v) })
+	if err != nil {
+		return nil, err
+	}
+	return v.([]string), nil
+}
+
+// GetStringMap returns the string map value of the given path.
+func (t *tree) GetStringMap(path string) map[string]string {
+	return t.GetStringMapOr(path, nil)
+}
+
+// GetStringMapOr returns the string map value of the given path or the given default value.
+func (t *tree) GetStringMapOr(path string, def map[string]string) map[string]string {
+	if v, err := t.GetStringMapE(path); err == nil {
+		return v
+	}
+	return def
+}
+
+// GetStringMapE returns the string map value of the given path or a *ValueError.
+func (t *tree) GetStringMapE(path string) (map[string]string, error) {
+	v, err := t.value(path, "map[string]string", func(v interface{}) (interface{}, error) { return toStringMap(v) })
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]string), nil
+}
+
+// The errUnsupportedType error reports that the type of the value cannot be converted.
+var errUnsupportedType = errors.New("unsupported type")
+
+// The parseInt function parses the given string as a decimal integer, the leading
+// zeros do not mean octal, such as "010" is 10. The hexadecimal integers prefixed
+// with "0x" are also supported.
+func parseInt(s string) (int64, error) {
+	n := strings.TrimLeft(s, "+-")
+	if strings.HasPrefix(n, "0x") || strings.HasPrefix(n, "0X") {
+		return strconv.ParseInt(s[:len(s)-len(n)]+n[2:], 16, 64)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// The toString function converts the given scalar value to a string.
+func toString(v interface{}) (string, error) {
+	switch o := v.(type) {
+	case string:
+		return o, nil
+	case []byte:
+		return string(o), nil
+	case bool:
+		return strconv.FormatBool(o), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return fmt.Sprint(o), nil
+	case float32:
+		return strconv.FormatFloat(float64(o), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(o, 'f', -1, 64), nil
+	case time.Time:
+		return o.Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return o.String(), nil
+	case fmt.Stringer:
+		return o.String(), nil
+	}
+	return "", errUnsupportedType
+}
+
+// The toInt64 function converts the given value to an int64.
+func toInt64(v interface{}) (int64, error) {
+	switch o := v.(type) {
+	case int:
+		return int64(o), nil
+	case int8:
+		return int64(o), nil
+	case int16:
+		return int64(o), nil
+	case int32:
+		return int64(o), nil
+	case int64:
+		return o, nil
+	case uint:
+		return uintToInt64(uint64(o))
+	case uint8:
+		return int64(o), nil
+	case uint16:
+		return int64(o), nil
+	case uint32:
+		return int64(o), nil
+	case uint64:
+		return uintToInt64(o)
+	case float32:
+		return floatToInt64(float64(o))
+	case float64:
+		return floatToInt64(o)
+	case json.Number:
+		return toInt64(string(o))
+	case time.Duration:
+		return int64(o), nil
+	case string:
+		s := strings.TrimSpace(o)
+		if n, err := parseInt(s); err == nil {
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, strconv.ErrSyntax
+		}
+		return floatToInt64(f)
+	}
+	return 0, errUnsupportedType
+}
+
+// The uintToInt64 function converts the given uint64 to an int64.
+func uintToInt64(n uint64) (int64, error) {
+	if n > math.MaxInt64 {
+		return 0, strconv.ErrRange
+	}
+	return int64(n), nil
+}
+
+// The floatToInt64 function converts the given integral float to an int64.
+func floatToInt64(f float64) (int64, error) {
+	if f != math.Trunc(f) {
+		return 0, errors.New("not an integer")
+	}
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, strconv.ErrRange
+	}
+	return int64(f), nil
+}
+
+// The toFloat64 function converts the given value to a float64.
+func toFloat64(v interface{}) (float64, error) {
+	switch o := v.(type) {
+	case float32:
+		return float64(o), nil
+	case float64:
+		return o, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return strconv.ParseFloat(fmt.Sprint(o), 64)
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(o), 64)
+		if err != nil {
+			return 0, strconv.ErrSyntax
+		}
+		return f, nil
+	}
+	return 0, errUnsupportedType
+}
+
+// The toBool function converts the given value to a bool.
+func toBool(v interface{}) (bool, error) {
+	switch o := v.(type) {
+	case bool:
+		return o, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(o)) {
+		case "1", "t", "true", "y", "yes", "on":
+			return true, nil
+		case "0", "f", "false", "n", "no", "off":
+			return false, nil
+		}
+		return false, strconv.ErrSyntax
+	}
+	if n, err := toInt64(v); err == nil {
+		switch n {
+		case 0:
+			return false, nil
+		case 1:
+			return true, nil
+		}
+		return false, strconv.ErrRange
+	}
+	return false, errUnsupportedType
+}
+
+// The toDuration function converts the given value to a time.Duration.
+func toDuration(v interface{}) (time.Duration, error) {
+	switch o := v.(type) {
+	case time.Duration:
+		return o, nil
+	case string:
+		s := strings.TrimSpace(o)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Duration(n), nil
+		}
+		return time.ParseDuration(s)
+	}
+	n, err := toInt64(v)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(n), nil
+}
+
+// The timeLayouts variable defines the supported time layouts.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// The toTime function converts the given value to a time.Time.
+func toTime(v interface{}) (time.Time, error) {
+	switch o := v.(type) {
+	case time.Time:
+		return o, nil
+	case string:
+		s := strings.TrimSpace(o)
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(n, 0), nil
+		}
+		return time.Time{}, errors.New("unsupported time layout")
+	}
+	n, err := toInt64(v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(n, 0), nil
+}
+
+// The byteSizeUnits variable defines the supported byte size units.
+var byteSizeUnits = map[string]uint64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1000,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1000 * 1000,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1000 * 1000 * 1000,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"tib": 1 << 40,
+	"p":   1 << 50,
+	"pb":  1000 * 1000 * 1000 * 1000 * 1000,
+	"pib": 1 << 50,
+}
+
+// The toByteSize function converts the given value to a byte size.
+func toByteSize(v interface{}) (uint64, error) {
+	s, ok := v.(string)
+	if !ok {
+		n, err := toInt64(v)
+		if err != nil {
+			return 0, err
+		}
+		if n < 0 {
+			return 0, strconv.ErrRange
+		}
+		return uint64(n), nil
+	}
+
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(s)
+	}
+	unit, found := byteSizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !found || i == 0 {
+		return 0, strconv.ErrSyntax
+	}
+	f, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, strconv.ErrSyntax
+	}
+	if f *= float64(unit); f >= math.MaxUint64 {
+		return 0, strconv.ErrRange
+	}
+	return uint64(f), nil
+}
+
+// The toStringSlice function converts the given value to a string slice.
+func toStringSlice(v interface{}) ([]string, error) {
+	switch o := v.(type) {
+	case []string:
+		return o, nil
+	case []interface{}:
+		r := make([]string, len(o))
+		for i, j := 0, len(o); i < j; i++ {
+			s, err := toString(o[i])
+			if err != nil {
+				return nil, err
+			}
+			r[i] = s
+		}
+		return r, nil
+	case string:
+		if strings.TrimSpace(o) == "" {
+			return []string{}, nil
+		}
+		r := strings.Split(o, ",")
+		for i, j := 0, len(r); i < j; i++ {
+			r[i] = strings.TrimSpace(r[i])
+		}
+		return r, nil
+	}
+	if s, err := toString(v); err == nil {
+		return []string{s}, nil
+	}
+	return nil, errUnsupportedType
+}
+
+// The toStringMap function converts the given value to a string map.
+func toStringMap(v interface{}) (map[string]string, error) {
+	switch o := v.(type) {
+	case map[string]string:
+		return o, nil
+	case map[string]interface{}:
+		r := make(map[string]string, len(o))
+		for k, v := range o {
+			s, err := toString(v)
+			if err != nil {
+				return nil, err
+			}
+			r[k] = s
+		}
+		return r, nil
+	}
+	return nil, errUnsupportedType
+}
@@ -0,0 +1,165 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTree_Getter(t *testing.T) {
+	item, err := NewFileItem("test/getter.yaml")
+	if err != nil {
+		t.Fatalf("NewFileItem(): %s", err)
+	}
+	o, err := AsFormatItem(item).Tree()
+	if err != nil {
+		t.Fatalf("Item.Tree(): %s", err)
+	}
+
+	if got := o.GetString("name"); got != "getter" {
+		t.Fatalf("Tree.GetString(): %s", got)
+	}
+	if got := o.GetString("port"); got != "8080" {
+		t.Fatalf("Tree.GetString(): %s", got)
+	}
+	if got := o.GetInt("port"); got != 8080 {
+		t.Fatalf("Tree.GetInt(): %d", got)
+	}
+	if got := o.GetFloat("ratio"); got != 0.5 {
+		t.Fatalf("Tree.GetFloat(): %f", got)
+	}
+	if got := o.GetBool("debug"); !got {
+		t.Fatalf("Tree.GetBool(): %v", got)
+	}
+	if got := o.GetDuration("timeout"); got != 30*time.Second {
+		t.Fatalf("Tree.GetDuration(): %s", got)
+	}
+	if got := o.GetTime("started"); !got.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("Tree.GetTime(): %s", got)
+	}
+	if got := o.GetByteSize("buffer"); got != 64<<20 {
+		t.Fatalf("Tree.GetByteSize(): %d", got)
+	}
+	if got := o.GetStringSlice("hosts"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("Tree.GetStringSlice(): %v", got)
+	}
+	if got := o.GetStringSlice("tags"); !reflect.DeepEqual(got, []string{"x", "y"}) {
+		t.Fatalf("Tree.GetStringSlice(): %v", got)
+	}
+	if got := o.GetStringMap("labels"); !reflect.DeepEqual(got, map[string]string{"env": "prod", "zone": "1"}) {
+		t.Fatalf("Tree.GetStringMap(): %v", got)
+	}
+
+	if got := o.GetIntOr("name", 1); got != 1 {
+		t.Fatalf("Tree.GetIntOr(): %d", got)
+	}
+	if got := o.GetStringOr("unknown", "default"); got != "default" {
+		t.Fatalf("Tree.GetStringOr(): %s", got)
+	}
+	if got := o.GetDurationOr("name", time.Second); got != time.Second {
+		t.Fatalf("Tree.GetDurationOr(): %s", got)
+	}
+	if got := o.GetInt("unknown"); got != 0 {
+		t.Fatalf("Tree.GetInt(): %d", got)
+	}
+
+	_, err = o.GetIntE("name")
+	if err == nil {
+		t.Fatal("Tree.GetIntE(): no error")
+	}
+	var e *ValueError
+	if !errors.As(err, &e) {
+		t.Fatalf("Tree.GetIntE(): %T", err)
+	}
+	if e.Path != "name" || e.Source != item.Path() || e.Value != "getter" || e.Type != "int" {
+		t.Fatalf("Tree.GetIntE(): %+v", e)
+	}
+	if !strings.Contains(err.Error(), item.Path()) {
+		t.Fatalf("Tree.GetIntE(): %s", err)
+	}
+
+	_, err = o.Sub("labels").GetBoolE("unknown")
+	if !errors.Is(err, ErrMissingValue) {
+		t.Fatalf("Tree.GetBoolE(): %v", err)
+	}
+	if errors.As(err, &e); e.Path != "labels.unknown" {
+		t.Fatalf("Tree.GetBoolE(): %s", e.Path)
+	}
+}
+
+func TestToByteSize(t *testing.T) {
+	items := map[interface{}]uint64{
+		"64":     64,
+		"64B":    64,
+		"1k":     1 << 10,
+		"1KB":    1000,
+		"1KiB":   1 << 10,
+		"1.5MiB": 3 << 19,
+		"2 GB":   2000 * 1000 * 1000,
+		"1T":     1 << 40,
+		1024:     1024,
+	}
+	for v, want := range items {
+		got, err := toByteSize(v)
+		if err != nil {
+			t.Fatalf("toByteSize(): %v %s", v, err)
+		}
+		if got != want {
+			t.Fatalf("toByteSize(): %v %d", v, got)
+		}
+	}
+
+	for _, v := range []interface{}{"", "MB", "1XB", "1.2.3", -1, true} {
+		if _, err := toByteSize(v); err == nil {
+			t.Fatalf("toByteSize(): %v no error", v)
+		}
+	}
+}
+
+func TestToInt64(t *testing.T) {
+	items := map[interface{}]int64{
+		"10":          10,
+		"0x10":        16,
+		"-0X10":       -16,
+		"010":         10,
+		"08080":       8080,
+		"1e3":         1000,
+		float64(3):    3,
+		uint64(7):     7,
+		time.Second:   int64(time.Second),
+		int8(-1):      -1,
+		float32(2):    2,
+		uint32(65535): 65535,
+	}
+	for v, want := range items {
+		got, err := toInt64(v)
+		if err != nil {
+			t.Fatalf("toInt64(): %v %s", v, err)
+		}
+		if got != want {
+			t.Fatalf("toInt64(): %v %d", v, got)
+		}
+	}
+
+	for _, v := range []interface{}{"a", 1.5, uint64(1 << 63), true, nil, "0b1", "0x"} {
+		if _, err := toInt64(v); err == nil {
+			t.Fatalf("toInt64(): %v no error", v)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The expandBraces function expands the brace alternatives of the given pattern.
+// For example: Given "*.{yml,yaml}", returns "*.yml" and "*.yaml".
+// The nested braces are supported, and the unmatched braces are kept as is.
+func expandBraces(pattern string) []string {
+	depth, start := 0, -1
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case isEscape(c):
+			i++
+		case c == '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case c == '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 {
+				continue
+			}
+			var r []string
+			prefix, suffix := pattern[:start], pattern[i+1:]
+			for _, alt := range splitAlternatives(pattern[start+1 : i]) {
+				r = append(r, expandBraces(prefix+alt+suffix)...)
+			}
+			return r
+		}
+	}
+	return []string{pattern}
+}
+
+// The splitAlternatives function splits the given brace content by the top-level commas.
+func splitAlternatives(s string) []string {
+	var r []string
+	depth, last := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case isEscape(c):
+			i++
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case c == ',':
+			if depth == 0 {
+				r = append(r, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(r, s[last:])
+}
+
+// The isEscape function determines whether the given character is the escape
+// character of the patterns. Like filepath.Match, the backslash is the path
+// separator instead of the escape character on Windows.
+func isEscape(c byte) bool {
+	return c == '\\' && filepath.Separator == '/'
+}
+
+// The checkPattern function checks the syntax of the given pattern.
+func checkPattern(pattern string) error {
+	for _, p := range expandBraces(pattern) {
+		for _, segment := range strings.Split(filepath.ToSlash(p), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// The globFiles function returns the file paths that match the given pattern.
+// In addition to the syntax of filepath.Glob, the "**" segments match zero or
+// more directories, and the brace alternatives are supported.
+func globFiles(pattern string) ([]string, error) {
+	if err := checkPattern(pattern); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var r []string
+	for _, p := range expandBraces(pattern) {
+		var matches []string
+		if strings.Contains(p, "**") {
+			matches = walkGlob(filepath.Clean(p))
+		} else {
+			matches, _ = filepath.Glob(p)
+		}
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				r = append(r, m)
+			}
+		}
+	}
+	sort.Strings(r)
+	return r, nil
+}
+
+// The walkGlob function walks the longest directory prefix without meta characters
+// of the given "**" pattern and returns the matched paths.
+func walkGlob(pattern string) []string {
+	root := globRoot(pattern)
+	var r []string
+	_ = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			// The inaccessible directories are skipped.
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matchPath(pattern, p) {
+			r = append(r, p)
+		}
+		return nil
+	})
+	return r
+}
+
+// The globRoot function returns the longest directory prefix without meta characters
+// of the given pattern.
+func globRoot(pattern string) string {
+	root := pattern
+	for hasMeta(root) {
+		dir := filepath.Dir(root)
+		// The volume name and the root directory, such as "C:\\" on Windows.
+		if dir == root {
+			break
+		}
+		root = dir
+	}
+	return root
+}
+
+// The matchPath function determines whether the given path matches the given
+// pattern, the "**" segments match zero or more path segments.
+// The brace alternatives are not expanded by this function.
+func matchPath(pattern, name string) bool {
+	return matchSegments(
+		strings.Split(filepath.ToSlash(pattern), "/"),
+		strings.Split(filepath.ToSlash(name), "/"),
+	)
+}
+
+// The matchSegments function matches the given path segments by the given
+// pattern segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Skip the consecutive "**" segments.
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// The matchExclude function determines whether the given file path matches the
+// given exclude pattern. The patterns without slashes match the base names.
+func matchExclude(pattern, name string) bool {
+	for _, p := range expandBraces(pattern) {
+		if !strings.Contains(filepath.ToSlash(p), "/") {
+			if ok, _ := path.Match(p, filepath.Base(name)); ok {
+				return true
+			}
+			continue
+		}
+		if matchPath(filepath.Clean(p), filepath.Clean(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// The ignoreRule type is a rule of the .gitignore-style ignore files.
+type ignoreRule struct {
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// The readIgnoreFile function reads the rules of the given .gitignore-style
+// ignore file, the rules are relative to the directory of the ignore file.
+func readIgnoreFile(name string) ([]ignoreRule, error) {
+	base, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if line[0] == '!' {
+			rule.negate, line = true, line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if strings.HasPrefix(line, "/") {
+			rule.anchored, line = true, strings.TrimLeft(line, "/")
+		} else if strings.Contains(line, "/") {
+			// The patterns with middle slashes are relative to the ignore file.
+			rule.anchored = true
+		}
+		if line == "" {
+			continue
+		}
+		if err := checkPattern(line); err != nil {
+			return nil, err
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// The match method determines whether the given absolute file path matches the
+// current rule. A file matches the rule if the file or any of its parent
+// directories matches the pattern.
+func (r ignoreRule) match(name string) bool {
+	rel, err := filepath.Rel(r.base, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for _, p := range expandBraces(r.pattern) {
+		// The last segment is the file itself, it does not match the directory rules.
+		n := len(segments)
+		if r.dirOnly {
+			n--
+		}
+		if !r.anchored {
+			for i := 0; i < n; i++ {
+				if ok, _ := path.Match(p, segments[i]); ok {
+					return true
+				}
+			}
+			continue
+		}
+		ps := strings.Split(p, "/")
+		for i := 1; i <= n; i++ {
+			if matchSegments(ps, segments[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The ignored function determines whether the given absolute file path is ignored
+// by the given rules, the last matched rule wins.
+func ignored(rules []ignoreRule, name string) bool {
+	var r bool
+	for _, rule := range rules {
+		if rule.match(name) {
+			r = !rule.negate
+		}
+	}
+	return r
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	items := map[string][]string{
+		"*.json":              {"*.json"},
+		"*.{yml,yaml}":        {"*.yml", "*.yaml"},
+		"{a,b}/{c,d}.json":    {"a/c.json", "a/d.json", "b/c.json", "b/d.json"},
+		"a.{json,{yml,yaml}}": {"a.json", "a.yml", "a.yaml"},
+		"a.{,json}":           {"a.", "a.json"},
+		"a}.{json":            {"a}.{json"},
+	}
+	// The backslash is the path separator instead of the escape character on Windows.
+	if filepath.Separator == '/' {
+		items[`conf\{a,b}.yaml`] = []string{`conf\{a,b}.yaml`}
+	} else {
+		items[`conf\{a,b}.yaml`] = []string{`conf\a.yaml`, `conf\b.yaml`}
+	}
+	for pattern, want := range items {
+		if got := expandBraces(pattern); !reflect.DeepEqual(got, want) {
+			t.Fatalf("expandBraces(): %s %v", pattern, got)
+		}
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+	root, err := filepath.Abs("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := map[string]string{
+		filepath.FromSlash("conf/**/*.yaml"):   "conf",
+		filepath.FromSlash("conf/db/*.yaml"):   filepath.FromSlash("conf/db"),
+		filepath.FromSlash("*/*.yaml"):         ".",
+		filepath.Join(root, "**", "*.yaml"):    root,
+		filepath.Join(root, "conf", "**", "*"): filepath.Join(root, "conf"),
+	}
+	for pattern, want := range items {
+		if got := globRoot(pattern); got != want {
+			t.Fatalf("globRoot(): %s %s", pattern, got)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	items := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"conf/**/*.yaml", "conf/a.yaml", true},
+		{"conf/**/*.yaml", "conf/a/b/c.yaml", true},
+		{"conf/**/*.yaml", "conf/a/b/c.json", false},
+		{"conf/**/*.yaml", "other/a.yaml", false},
+		{"**/*.example.yaml", "conf/a.example.yaml", true},
+		{"**/*.example.yaml", "/abs/conf/a.example.yaml", true},
+		{"conf/**", "conf/a/b", true},
+		{"conf/**/**/b", "conf/b", true},
+		{"conf/*/b", "conf/a/c/b", false},
+	}
+	for _, item := range items {
+		if got := matchPath(item.pattern, item.name); got != item.want {
+			t.Fatalf("matchPath(): %s %s %v", item.pattern, item.name, got)
+		}
+	}
+}
+
+func TestGlobFiles(t *testing.T) {
+	got, err := globFiles("test/glob/**/*.{yml,json}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"test/glob/conf/cache/cache.json",
+		"test/glob/conf/db/db.example.yml",
+		"test/glob/conf/db/db.yml",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("globFiles(): %v", got)
+	}
+	if _, err := globFiles("test/**/["); err == nil {
+		t.Fatal("globFiles(): no error")
+	}
+}
+
+func TestReadIgnoreFile(t *testing.T) {
+	rules, err := readIgnoreFile("test/glob/conf/.configignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 || !rules[0].dirOnly || !rules[2].negate {
+		t.Fatalf("readIgnoreFile(): %v", rules)
+	}
+	if _, err := readIgnoreFile("test/glob/conf/.notfound"); err == nil {
+		t.Fatal("readIgnoreFile(): no error")
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DecodeHook interface defines the hook of the format independent decoder.
+type DecodeHook interface {
+	// Convert converts the given raw config value to the given type.
+	// If the hook does not support the given type, it returns false.
+	Convert(reflect.Type, interface{}) (interface{}, bool, error)
+}
+
+// DecodeHookFunc type defines the decode hook function.
+type DecodeHookFunc func(reflect.Type, interface{}) (interface{}, bool, error)
+
+// Convert converts the given raw config value to the given type.
+func (f DecodeHookFunc) Convert(t reflect.Type, v interface{}) (interface{}, bool, error) {
+	return f(t, v)
+}
+
+// ByteSize type defines the byte size, such as "64MiB", "64MB" and "64M".
+// The "K", "KiB" units are 1024 bytes, and the "KB" unit is 1000 bytes.
+type ByteSize uint64
+
+// UnmarshalText parses the given byte size text.
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	n, err := toByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*s = ByteSize(n)
+	return nil
+}
+
+// TimeHook creates and returns a DecodeHook that converts the strings to time.Time
+// by the given layouts. The built-in time layouts are used if none of the given
+// layouts match.
+func TimeHook(layouts ...string) DecodeHook {
+	return DecodeHookFunc(func(t reflect.Type, v interface{}) (interface{}, bool, error) {
+		if t != timeType {
+			return nil, false, nil
+		}
+		if s, ok := v.(string); ok {
+			for _, layout := range layouts {
+				if r, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+					return r, true, nil
+				}
+			}
+		}
+		r, err := toTime(v)
+		return r, true, err
+	})
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	byteSizeType = reflect.TypeOf(ByteSize(0))
+	ipType       = reflect.TypeOf(net.IP{})
+	ipNetType    = reflect.TypeOf(net.IPNet{})
+	urlType      = reflect.TypeOf(url.URL{})
+	regexpType   = reflect.TypeOf(regexp.Regexp{})
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+)
+
+// The builtinHooks variable defines the built-in decode hooks, they are used after
+// the hooks of the decode options.
+var builtinHooks = []DecodeHook{
+	TimeHook(),
+	DecodeHookFunc(convertBuiltin),
+}
+
+// The convertBuiltin function converts the given raw config value to the built-in
+// supported types. The pointer types, such as *url.URL, are also supported.
+func convertBuiltin(t reflect.Type, v interface{}) (interface{}, bool, error) {
+	pointer := t.Kind() == reflect.Ptr
+	if pointer {
+		t = t.Elem()
+	}
+	var (
+		r   interface{}
+		err error
+	)
+	switch t {
+	case durationType:
+		var d time.Duration
+		d, err = toDuration(v)
+		r = &d
+	case byteSizeType:
+		var n uint64
+		n, err = toByteSize(v)
+		s := ByteSize(n)
+		r = &s
+	case ipType:
+		var s string
+		if s, err = toString(v); err == nil {
+			ip := net.ParseIP(strings.TrimSpace(s))
+			if ip == nil {
+				err = errors.New("invalid ip address")
+			}
+			r = &ip
+		}
+	case ipNetType:
+		var s string
+		if s, err = toString(v); err == nil {
+			r, err = parseCIDR(strings.TrimSpace(s))
+		}
+	case urlType:
+		var s string
+		if s, err = toString(v); err == nil {
+			r, err = url.Parse(strings.TrimSpace(s))
+		}
+	case regexpType:
+		var s string
+		if s, err = toString(v); err == nil {
+			r, err = regexp.Compile(s)
+		}
+	case bigIntType:
+		var s string
+		if s, err = toString(v); err == nil {
+			n, ok := new(big.Int).SetString(strings.TrimSpace(s), 0)
+			if !ok {
+				err = errors.New("invalid integer")
+			}
+			r = n
+		}
+	case bigFloatType:
+		var s string
+		if s, err = toString(v); err == nil {
+			r, _, err = big.ParseFloat(strings.TrimSpace(s), 10, 0, big.ToNearestEven)
+		}
+	default:
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	// The converted values are pointers, dereference them for the value types.
+	if !pointer {
+		return reflect.ValueOf(r).Elem().Interface(), true, nil
+	}
+	return r, true, nil
+}
+
+// The parseCIDR function parses the given CIDR notation, the ip address of the
+// network is kept, such as "192.168.0.1/24".
+func parseCIDR(s string) (*net.IPNet, error) {
+	ip, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, err
+	}
+	n.IP = ip
+	return n, nil
+}
+
+// The hook method converts the given raw config value by the decode hooks.
+// It returns false if no decode hook supports the type of the given value.
+func (d *treeDecoder) hook(v reflect.Value, raw interface{}, path string) (bool, error) {
+	for _, hooks := range [][]DecodeHook{d.options.Hooks, builtinHooks} {
+		for _, hook := range hooks {
+			r, ok, err := hook.Convert(v.Type(), raw)
+			if !ok {
+				continue
+			}
+			if err != nil {
+				return true, d.error(v, raw, path, err)
+			}
+			if r == nil {
+				v.Set(reflect.Zero(v.Type()))
+				return true, nil
+			}
+			rv := reflect.ValueOf(r)
+			if !rv.Type().AssignableTo(v.Type()) {
+				if !rv.Type().ConvertibleTo(v.Type()) {
+					return true, d.error(v, raw, path, fmt.Errorf("decode hook returns %T", r))
+				}
+				rv = rv.Convert(v.Type())
+			}
+			v.Set(rv)
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testHookTypes struct {
+	Timeout   time.Duration   `config:"timeout"`
+	Created   time.Time       `config:"created"`
+	Birthday  *time.Time      `config:"birthday"`
+	IP        net.IP          `config:"ip"`
+	Network   *net.IPNet      `config:"network"`
+	Endpoint  *url.URL        `config:"endpoint"`
+	Pattern   *regexp.Regexp  `config:"pattern"`
+	Total     *big.Int        `config:"total"`
+	Ratio     big.Float       `config:"ratio"`
+	Buffer    ByteSize        `config:"buffer"`
+	Level     testDecodeLevel `config:"level"`
+	Intervals []time.Duration `config:"intervals"`
+}
+
+func TestDecodeTree_Hooks(t *testing.T) {
+	options := &DecodeOptions{Hooks: []DecodeHook{TimeHook("02/01/2006")}}
+	for _, ext := range []string{"json", "yaml", "toml", "xml"} {
+		item, err := NewFileItem("test/hooks/types." + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v testHookTypes
+		if err := DecodeItem(item, &v, options); err != nil {
+			t.Fatalf("DecodeItem(): %s %s", ext, err)
+		}
+		if v.Timeout != 90*time.Second ||
+			!v.Created.Equal(time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)) ||
+			v.Birthday == nil || !v.Birthday.Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)) ||
+			!v.IP.Equal(net.IPv4(192, 168, 0, 1)) ||
+			v.Network.String() != "10.0.0.1/8" || !v.Network.Contains(net.IPv4(10, 1, 2, 3)) ||
+			v.Endpoint.Port() != "8080" || v.Endpoint.Query().Get("q") != "1" ||
+			!v.Pattern.MatchString("abc") || v.Pattern.MatchString("ABC") ||
+			v.Total.String() != "123456789012345678901234567890" ||
+			v.Ratio.String() != "1.25" || v.Buffer != 64<<20 || v.Level != 2 ||
+			!reflect.DeepEqual(v.Intervals, []time.Duration{time.Second, 2 * time.Minute}) {
+			t.Fatalf("DecodeItem(): %s %+v", ext, v)
+		}
+	}
+}
+
+func TestDecodeTree_HookErrors(t *testing.T) {
+	items := map[string]interface{}{
+		`{"v": "1x"}`:      new(time.Duration),
+		`{"v": "a"}`:       new(time.Time),
+		`{"v": "1.2.3"}`:   new(net.IP),
+		`{"v": "1.2.3.4"}`: new(net.IPNet),
+		`{"v": ":"}`:       new(*url.URL),
+		`{"v": "("}`:       new(regexp.Regexp),
+		`{"v": "1.5"}`:     new(big.Int),
+		`{"v": "b"}`:       new(*big.Float),
+		`{"v": "1XB"}`:     new(ByteSize),
+	}
+	for s, p := range items {
+		tree, _ := AsFormatItem(NewItemFromString(s)).Tree()
+		v := reflect.New(reflect.StructOf([]reflect.StructField{{
+			Name: "V",
+			Type: reflect.TypeOf(p).Elem(),
+			Tag:  `config:"v"`,
+		}}))
+		var e *ValueError
+		if err := DecodeTree(tree, v.Interface(), nil); !errors.As(err, &e) || e.Path != "v" {
+			t.Fatalf("DecodeTree(): %s %T %v", s, p, err)
+		}
+	}
+}
+
+func TestDecodeTree_CustomHook(t *testing.T) {
+	type upper string
+	hook := DecodeHookFunc(func(t reflect.Type, v interface{}) (interface{}, bool, error) {
+		if t != reflect.TypeOf(upper("")) {
+			return nil, false, nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, true, errors.New("not a string")
+		}
+		// The converted value is converted to the target type.
+		return strings.ToUpper(s), true, nil
+	})
+	var v struct {
+		Name  upper         `config:"name"`
+		Names []upper       `config:"names"`
+		Wait  time.Duration `config:"wait"`
+	}
+	tree, _ := AsFormatItem(NewItemFromString(`{"name": "a", "names": ["b", "c"], "wait": 1000}`)).Tree()
+	if err := DecodeTree(tree, &v, &DecodeOptions{Hooks: []DecodeHook{hook}}); err != nil {
+		t.Fatalf("DecodeTree(): %s", err)
+	}
+	if v.Name != "A" || !reflect.DeepEqual(v.Names, []upper{"B", "C"}) || v.Wait != time.Microsecond {
+		t.Fatalf("DecodeTree(): %+v", v)
+	}
+	tree, _ = AsFormatItem(NewItemFromString(`{"name": 1}`)).Tree()
+	if err := DecodeTree(tree, &v, &DecodeOptions{Hooks: []DecodeHook{hook}}); err == nil {
+		t.Fatal("DecodeTree(): no error")
+	}
+}
+
+func TestByteSize_UnmarshalText(t *testing.T) {
+	var v struct {
+		Size ByteSize `json:"size"`
+	}
+	if err := NewItemFromString(`{"size": "1KB"}`).JSON(&v); err != nil || v.Size != 1000 {
+		t.Fatalf("ByteSize.UnmarshalText(): %v %d", err, v.Size)
+	}
+	if err := NewItemFromString(`{"size": "1XB"}`).JSON(&v); err == nil {
+		t.Fatal("ByteSize.UnmarshalText(): no error")
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// IncludeKey is the top-level key of the config documents used to declare the
+// included config targets, for example: {"$include": ["common", "db.*"]}.
+// The included config targets are loaded through the same loaders and merged in
+// order before the including document, so the including document has the highest
+// priority. The variables are expanded once after merging, as a part of the
+// including document. The config target names containing the magic characters
+// recognized by path.Match are matched against the config targets registered in
+// the file loaders.
+const IncludeKey = "$include"
+
+// IncludeError reports that the included config targets cannot be resolved.
+type IncludeError struct {
+	// Chain is the include chain, the last one is the config target that cannot
+	// be included.
+	Chain []string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("configurator: include %s: %s", strings.Join(e.Chain, " -> "), e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *IncludeError) Unwrap() error {
+	return e.Err
+}
+
+// The include method resolves the included config targets of the given config item
+// and merges them. If there is no included config target, the given config item is
+// returned directly.
+func (o *configurator) include(item Item, chain []string) (Item, error) {
+	fi := AsFormatItem(item)
+	if !bytes.Contains(item.Bytes(), []byte(IncludeKey)) || lookupFormat(fi.Format()) == nil {
+		return item, nil
+	}
+	m, err := itemMap(item)
+	if err != nil {
+		return nil, err
+	}
+	v, found := m[IncludeKey]
+	if !found {
+		return item, nil
+	}
+	delete(m, IncludeKey)
+
+	var names []string
+	switch o := v.(type) {
+	case string:
+		names = []string{o}
+	case []interface{}:
+		for i, j := 0, len(o); i < j; i++ {
+			s, ok := o[i].(string)
+			if !ok {
+				return nil, &IncludeError{Chain: chain, Err: fmt.Errorf("invalid %s value %v", IncludeKey, o[i])}
+			}
+			names = append(names, s)
+		}
+	default:
+		return nil, &IncludeError{Chain: chain, Err: fmt.Errorf("invalid %s value %v", IncludeKey, v)}
+	}
+
+	var r map[string]interface{}
+	for _, name := range names {
+		targets := []string{name}
+		if hasMeta(name) {
+			if targets, err = o.matchTargets(name); err != nil {
+				return nil, &IncludeError{Chain: chain, Err: err}
+			}
+		}
+		for _, target := range targets {
+			next := append(chain[:len(chain):len(chain)], target)
+			if inChain(chain, target) {
+				// The pattern may match the config targets being included.
+				if hasMeta(name) {
+					continue
+				}
+				return nil, &IncludeError{Chain: next, Err: errors.New("include cycle")}
+			}
+			o.addDependency(target, chain[len(chain)-1])
+			included, loader, err := o.loadInclude(target, chain)
+			if err != nil {
+				var e *IncludeError
+				if errors.As(err, &e) {
+					return nil, err
+				}
+				return nil, &IncludeError{Chain: next, Err: err}
+			}
+			// The json.Number values are kept only if they are encoded to JSON again.
+			var im map[string]interface{}
+			if fi.Format() == FormatJSON {
+				im, err = itemMap(included)
+			} else {
+				var it Tree
+				if it, err = AsFormatItem(included).Tree(); err == nil {
+					im = it.Map()
+				}
+			}
+			if err != nil {
+				return nil, &IncludeError{Chain: next, Err: withTarget(err, target, loader)}
+			}
+			r = Merge(r, im, nil)
+		}
+	}
+
+	data, err := encodeFormat(fi.Format(), Merge(r, m, nil))
+	if err != nil {
+		return nil, err
+	}
+	return withContent(item, data), nil
+}
+
+// The loadInclude method loads the given included config target and resolves its
+// included config targets. The variables are not expanded and the config item is
+// not validated, the merged config item is processed by the including config target.
+func (o *configurator) loadInclude(target string, chain []string) (Item, Loader, error) {
+	item, loader, err := o.loadRaw(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	if item, err = o.include(item, append(chain[:len(chain):len(chain)], target)); err != nil {
+		return nil, nil, withTarget(err, target, loader)
+	}
+	return item, loader, nil
+}
+
+// The matchTargets method returns the sorted config target names that match the
+// given pattern in the registered file loaders.
+func (o *configurator) matchTargets(pattern string) ([]string, error) {
+	found := make(map[string]bool)
+	for _, loader := range o.loaders {
+		if l, ok := loader.(*fileLoader); ok {
+			for _, name := range l.names() {
+				matched, err := path.Match(pattern, name)
+				if err != nil {
+					return nil, err
+				}
+				if matched {
+					found[name] = true
+				}
+			}
+		}
+	}
+
+	targets := make([]string, 0, len(found))
+	for name := range found {
+		targets = append(targets, name)
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+// The inChain function determines whether the given config target is in the given chain.
+func inChain(chain []string, target string) bool {
+	for _, s := range chain {
+		if s == target {
+			return true
+		}
+	}
+	return false
+}
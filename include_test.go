@@ -0,0 +1,145 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConfigurator_Include(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/include/*"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{"app", "nested"} {
+		item, err := o.Load(target)
+		if err != nil {
+			t.Fatalf("Configurator.Load(): %s", err)
+		}
+		if _, ok := item.(FileItem); !ok {
+			t.Fatalf("Configurator.Load(): %T", item)
+		}
+		tree, err := AsFormatItem(item).Tree()
+		if err != nil {
+			t.Fatalf("Item.Tree(): %s", err)
+		}
+		if tree.Has(IncludeKey) {
+			t.Fatalf("Configurator.Load(): %s", item)
+		}
+		items := map[string]string{
+			"name":            target,
+			"log.level":       "debug",
+			"log.format":      "json",
+			"db.primary.host": "10.0.0.1",
+			"db.replica.host": "10.0.0.2",
+		}
+		for path, want := range items {
+			if got := tree.GetString(path); got != want {
+				t.Fatalf("Configurator.Load(): %s %s %q", target, path, got)
+			}
+		}
+	}
+}
+
+func TestConfigurator_IncludeInterpolation(t *testing.T) {
+	o := New().SetInterpolation(true)
+	if err := o.AddFile("test/include/*"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The escaped variables of the included config targets are expanded only once.
+	for _, target := range []string{"common", "app", "nested"} {
+		tree, err := o.LoadTree(target)
+		if err != nil {
+			t.Fatalf("Configurator.LoadTree(): %s", err)
+		}
+		if got := tree.GetString("home"); got != "${HOME}" {
+			t.Fatalf("Configurator.LoadTree(): %s %q", target, got)
+		}
+	}
+}
+
+func TestConfigurator_IncludeError(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/include/*"); err != nil {
+		t.Fatal(err)
+	}
+
+	var e *IncludeError
+	_, err := o.Load("cycle")
+	if !errors.As(err, &e) || strings.Join(e.Chain, ",") != "cycle,cycle2,cycle" {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	_, err = o.Load("missing")
+	if !errors.As(err, &e) || !errors.Is(err, ErrNotFound) || strings.Join(e.Chain, ",") != "missing,unknown" {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	if _, err = o.Load("invalid"); !errors.As(err, &e) {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+}
+
+func TestConfigurator_IncludeInvalidate(t *testing.T) {
+	l := new(mapLoader)
+	l.set("base", `{"name": "base1"}`)
+	l.set("app", `{"$include": "base"}`)
+	o := New().Use(l).SetCache(&CacheOptions{})
+
+	var changes []*Change
+	o.OnChange("app", func(c *Change) { changes = append(changes, c) })
+
+	tree, err := o.LoadTree("app")
+	if err != nil || tree.GetString("name") != "base1" {
+		t.Fatalf("Configurator.LoadTree(): %v", err)
+	}
+	l.set("base", `{"name": "base2"}`)
+	o.Invalidate("base")
+	tree, err = o.LoadTree("app")
+	if err != nil || tree.GetString("name") != "base2" {
+		t.Fatalf("Configurator.LoadTree(): %v", err)
+	}
+
+	l.set("base", `{"name": "base3"}`)
+	o.(*configurator).reloadChanged("base")
+	if len(changes) != 1 || !strings.Contains(changes[0].New.String(), "base3") {
+		t.Fatalf("Configurator.OnChange(): %d", len(changes))
+	}
+}
+
+func TestConfigurator_IncludeNumbers(t *testing.T) {
+	l := new(mapLoader)
+	l.set("common", `{"a": 1, "n": 9007199254740995}`)
+	l.set("app", `{"$include": "common", "id": 9007199254740993, "big": 1e23}`)
+	o := New().Use(l)
+
+	item, err := o.Load("app")
+	if err != nil {
+		t.Fatalf("Configurator.Load(): %s", err)
+	}
+	want := `{"a":1,"big":1e23,"id":9007199254740993,"n":9007199254740995}`
+	if got := item.String(); got != want {
+		t.Fatalf("Configurator.Load(): %s", got)
+	}
+	var v struct {
+		ID int64 `json:"id"`
+		N  int64 `json:"n"`
+	}
+	if err := o.LoadJSON("app", &v); err != nil || v.ID != 9007199254740993 || v.N != 9007199254740995 {
+		t.Fatalf("Configurator.LoadJSON(): %v %+v", err, v)
+	}
+}
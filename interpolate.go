@@ -0,0 +1,394 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Resolver interface defines the variable resolver used by the interpolation.
+type Resolver interface {
+	// Resolve returns the value of the given variable key.
+	// If the variable does not exist, found is false.
+	Resolve(string) (value string, found bool, err error)
+}
+
+// ResolverFunc type defines the function variable resolver.
+type ResolverFunc func(string) (string, bool, error)
+
+// Resolve returns the value of the given variable key.
+func (f ResolverFunc) Resolve(key string) (string, bool, error) {
+	return f(key)
+}
+
+// InterpolationError reports that a variable of the config item cannot be expanded.
+type InterpolationError struct {
+	// Expr is the variable expression, such as "${NAME:?message}".
+	Expr string
+
+	// Chain is the list of the config targets being interpolated, the last one is
+	// the config target that contains the variable.
+	Chain []string
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns the error message.
+func (e *InterpolationError) Error() string {
+	return fmt.Sprintf("configurator: interpolate %s in %s: %s", e.Expr, strings.Join(e.Chain, " -> "), e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *InterpolationError) Unwrap() error {
+	return e.Err
+}
+
+// SetInterpolation enables or disables the variable interpolation.
+func (o *configurator) SetInterpolation(enabled bool) Configurator {
+	o.mutex.Lock()
+	o.interpolation = enabled
+	o.mutex.Unlock()
+	o.Purge()
+	return o
+}
+
+// SetResolver registers the variable resolver of the given scheme.
+// The built-in "env" and "ref" schemes can not be replaced.
+func (o *configurator) SetResolver(scheme string, r Resolver) Configurator {
+	if scheme == "env" || scheme == "ref" {
+		return o
+	}
+	o.mutex.Lock()
+	if o.resolvers == nil {
+		o.resolvers = make(map[string]Resolver)
+	}
+	o.resolvers[scheme] = r
+	o.mutex.Unlock()
+	return o
+}
+
+// The interpolationEnabled method determines whether the interpolation is enabled.
+func (o *configurator) interpolationEnabled() bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.interpolation
+}
+
+// The interpolate method expands the variables of the given config item.
+// If there is no variable, the given config item is returned directly.
+func (o *configurator) interpolate(item Item, chain []string) (Item, error) {
+	fi := AsFormatItem(item)
+	if !bytes.Contains(item.Bytes(), []byte("${")) || lookupFormat(fi.Format()) == nil {
+		return item, nil
+	}
+
+	ip := &interpolator{o: o, chain: chain}
+	// For xml, the variables are expanded on the token stream, so that the
+	// structure of the document is retained.
+	if fi.Format() == FormatXML {
+		data, changed, err := ip.expandXML(item.Bytes())
+		if err != nil || !changed {
+			return item, err
+		}
+		return withContent(item, data), nil
+	}
+
+	t, err := fi.Tree()
+	if err != nil {
+		return nil, err
+	}
+	m := t.Map()
+	if fi.Format() == FormatJSON {
+		// The json numbers are decoded as json.Number, so that the integers are not
+		// converted to float64 and the original numbers are encoded.
+		if m, err = unmarshalJSONNumber(item.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	v, changed, err := ip.expandValue(m)
+	if err != nil || !changed {
+		return item, err
+	}
+	data, err := encodeFormat(fi.Format(), v)
+	if err != nil {
+		return nil, err
+	}
+	return withContent(item, data), nil
+}
+
+// The interpolator type expands the variables of a config item.
+type interpolator struct {
+	o     *configurator
+	chain []string
+}
+
+// The expandValue method expands the variables of all string values of the given
+// config tree value.
+func (ip *interpolator) expandValue(v interface{}) (interface{}, bool, error) {
+	switch o := v.(type) {
+	case string:
+		if !strings.Contains(o, "${") {
+			return o, false, nil
+		}
+		r, err := ip.expand(o)
+		return r, err == nil, err
+	case map[string]interface{}:
+		changed := false
+		for k, v := range o {
+			r, c, err := ip.expandValue(v)
+			if err != nil {
+				return nil, false, err
+			}
+			if c {
+				o[k], changed = r, true
+			}
+		}
+		return o, changed, nil
+	case []interface{}:
+		changed := false
+		for i, j := 0, len(o); i < j; i++ {
+			r, c, err := ip.expandValue(o[i])
+			if err != nil {
+				return nil, false, err
+			}
+			if c {
+				o[i], changed = r, true
+			}
+		}
+		return o, changed, nil
+	}
+	return v, false, nil
+}
+
+// The expandXML method expands the variables of the attribute values and the
+// text of the given xml document.
+func (ip *interpolator) expandXML(data []byte) ([]byte, bool, error) {
+	expand := func(s string) (string, bool, error) {
+		if !strings.Contains(s, "${") {
+			return s, false, nil
+		}
+		v, err := ip.expand(s)
+		if err != nil {
+			return "", false, err
+		}
+		r, err := toString(v)
+		if err != nil {
+			r = fmt.Sprint(v)
+		}
+		return r, true, nil
+	}
+
+	buf := new(bytes.Buffer)
+	d := xml.NewDecoder(bytes.NewReader(data))
+	e := xml.NewEncoder(buf)
+	changed := false
+	for {
+		t, err := d.RawToken()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, false, err
+		}
+		switch o := t.(type) {
+		case xml.StartElement:
+			o = o.Copy()
+			for i, j := 0, len(o.Attr); i < j; i++ {
+				s, c, err := expand(o.Attr[i].Value)
+				if err != nil {
+					return nil, false, err
+				}
+				o.Attr[i].Value, changed = s, changed || c
+			}
+			t = o
+		case xml.CharData:
+			s, c, err := expand(string(o))
+			if err != nil {
+				return nil, false, err
+			}
+			t, changed = xml.CharData(s), changed || c
+		}
+		if err := e.EncodeToken(xml.CopyToken(t)); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := e.Flush(); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), changed, nil
+}
+
+// The expand method expands all variables of the given string.
+// If the given string is exactly one variable, the resolved value is returned
+// without being converted to a string.
+func (ip *interpolator) expand(s string) (interface{}, error) {
+	buf := new(strings.Builder)
+	for i := 0; i < len(s); {
+		if s[i] != '$' || i+1 == len(s) {
+			buf.WriteByte(s[i])
+			i++
+			continue
+		}
+		// The "$${" is the escaped "${".
+		if strings.HasPrefix(s[i:], "$${") {
+			buf.WriteString("${")
+			i += 3
+			continue
+		}
+		if s[i+1] != '{' {
+			buf.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		j := matchBrace(s, i+1)
+		if j == -1 {
+			return nil, &InterpolationError{Expr: s[i:], Chain: ip.chain, Err: errors.New("unclosed variable")}
+		}
+		v, err := ip.resolve(s[i : j+1])
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && j == len(s)-1 {
+			if _, ok := v.(string); !ok {
+				return v, nil
+			}
+		}
+		if r, err := toString(v); err == nil {
+			buf.WriteString(r)
+		} else {
+			buf.WriteString(fmt.Sprint(v))
+		}
+		i = j + 1
+	}
+	return buf.String(), nil
+}
+
+// The matchBrace function returns the index of the brace matching the opening
+// brace at the given index, -1 if not found.
+func matchBrace(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// The resolve method resolves the given variable expression, such as "${NAME:-default}".
+func (ip *interpolator) resolve(expr string) (interface{}, error) {
+	body := expr[2 : len(expr)-1]
+	name, modifier, operand := body, "", ""
+	for i, depth := 0, 0; i < len(body)-1; i++ {
+		switch body[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ':':
+			if depth == 0 && (body[i+1] == '-' || body[i+1] == '?') {
+				name, modifier, operand = body[:i], body[i:i+2], body[i+2:]
+				i = len(body)
+			}
+		}
+	}
+
+	v, found, err := ip.lookup(name)
+	if err != nil {
+		return nil, &InterpolationError{Expr: expr, Chain: ip.chain, Err: err}
+	}
+	if found && v != "" {
+		return v, nil
+	}
+	switch modifier {
+	case ":-":
+		return ip.expand(operand)
+	case ":?":
+		if operand == "" {
+			operand = "variable is not set"
+		}
+		return nil, &InterpolationError{Expr: expr, Chain: ip.chain, Err: errors.New(operand)}
+	}
+	if found {
+		return v, nil
+	}
+	return "", nil
+}
+
+// The lookup method returns the value of the given variable name.
+func (ip *interpolator) lookup(name string) (interface{}, bool, error) {
+	scheme, key := "", name
+	if i := strings.IndexByte(name, ':'); i > 0 {
+		scheme, key = name[:i], name[i+1:]
+	}
+
+	switch scheme {
+	case "ref":
+		return ip.lookupRef(key)
+	case "env":
+		v, found := os.LookupEnv(key)
+		return v, found, nil
+	}
+
+	ip.o.mutex.Lock()
+	r := ip.o.resolvers[scheme]
+	ip.o.mutex.Unlock()
+	if r == nil {
+		// The colon is part of the environment variable name.
+		v, found := os.LookupEnv(name)
+		return v, found, nil
+	}
+	v, found, err := r.Resolve(key)
+	return v, found, err
+}
+
+// The lookupRef method returns the value of the given reference, such as "target#path".
+func (ip *interpolator) lookupRef(ref string) (interface{}, bool, error) {
+	target, path := ref, ""
+	if i := strings.IndexByte(ref, '#'); i != -1 {
+		target, path = ref[:i], ref[i+1:]
+	}
+	if inChain(ip.chain, target) {
+		return nil, false, fmt.Errorf("reference cycle %s -> %s", strings.Join(ip.chain, " -> "), target)
+	}
+
+	ip.o.addDependency(target, ip.chain[len(ip.chain)-1])
+	item, _, err := ip.o.loadChain(target, ip.chain)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	t, err := AsFormatItem(item).Tree()
+	if err != nil {
+		return nil, false, err
+	}
+	v, found := t.Get(path)
+	return v, found, nil
+}
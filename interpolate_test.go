@@ -0,0 +1,148 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newInterpolationConfigurator(t *testing.T) Configurator {
+	o := New().SetInterpolation(true)
+	if err := o.AddFile("test/interpolate/*"); err != nil {
+		t.Fatal(err)
+	}
+	o.SetResolver("vault", ResolverFunc(func(key string) (string, bool, error) {
+		return "secret-" + key, true, nil
+	}))
+	return o
+}
+
+func TestConfigurator_SetInterpolation(t *testing.T) {
+	defer setenv(t, map[string]string{"ZKITS_TEST_NAME": "app", "ZKITS_TEST_HOST": "db.local"})()
+
+	o := newInterpolationConfigurator(t)
+	tree, err := o.LoadTree("app.yaml")
+	if err != nil {
+		t.Fatalf("Configurator.LoadTree(): %s", err)
+	}
+	items := map[string]string{
+		"name":    "app",
+		"port":    "8080",
+		"host":    "db.local",
+		"escaped": "${ZKITS_TEST_NAME}",
+		"dsn":     "mysql://db.local:3306/secret-db",
+	}
+	for path, want := range items {
+		if got := tree.GetString(path); got != want {
+			t.Fatalf("Configurator.SetInterpolation(): %s %q", path, got)
+		}
+	}
+	// The referenced value keeps its type.
+	if got := tree.GetInt("database.weight"); got != 10 {
+		t.Fatalf("Configurator.SetInterpolation(): %d", got)
+	}
+
+	item, err := o.Load("app.xml")
+	if err != nil {
+		t.Fatalf("Configurator.Load(): %s", err)
+	}
+	if _, ok := item.(FileItem); !ok {
+		t.Fatalf("Configurator.Load(): %T", item)
+	}
+	var v struct {
+		Name string `xml:"name,attr"`
+		Port int    `xml:"port"`
+		Host string `xml:"host"`
+	}
+	if err := item.XML(&v); err != nil {
+		t.Fatalf("Item.XML(): %s", err)
+	}
+	if v.Name != "app" || v.Port != 8080 || v.Host != "db.local" {
+		t.Fatalf("Configurator.SetInterpolation(): %+v", v)
+	}
+
+	o.SetInterpolation(false)
+	if tree, err := o.LoadTree("app.yaml"); err != nil || tree.GetString("name") != "${ZKITS_TEST_NAME}" {
+		t.Fatalf("Configurator.SetInterpolation(): %v", err)
+	}
+}
+
+func TestConfigurator_InterpolationError(t *testing.T) {
+	o := newInterpolationConfigurator(t)
+
+	_, err := o.Load("required")
+	var e *InterpolationError
+	if !errors.As(err, &e) {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	if e.Err.Error() != "name is required" || strings.Join(e.Chain, ",") != "required" {
+		t.Fatalf("Configurator.Load(): %s", err)
+	}
+
+	_, err = o.Load("cycle")
+	if !errors.As(err, &e) || !strings.Contains(err.Error(), "cycle -> cycle2 -> cycle") {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+
+	ip := &interpolator{o: o.(*configurator)}
+	if _, err := ip.expand("${unclosed"); err == nil {
+		t.Fatal("interpolator.expand(): no error")
+	}
+}
+
+func TestInterpolator_Expand(t *testing.T) {
+	defer setenv(t, map[string]string{"ZKITS_TEST_A": "a", "ZKITS_TEST_EMPTY": ""})()
+
+	ip := &interpolator{o: New().(*configurator)}
+	items := map[string]string{
+		"":                                     "",
+		"$":                                    "$",
+		"a$b":                                  "a$b",
+		"${ZKITS_TEST_A}":                      "a",
+		"x${ZKITS_TEST_A}y":                    "xay",
+		"${ZKITS_TEST_EMPTY:-d}":               "d",
+		"${ZKITS_TEST_UNSET:-${ZKITS_TEST_A}}": "a",
+		"${ZKITS_TEST_UNSET}":                  "",
+		"$${ZKITS_TEST_A}":                     "${ZKITS_TEST_A}",
+		"${unknown:x}":                         "",
+	}
+	for s, want := range items {
+		v, err := ip.expand(s)
+		if err != nil {
+			t.Fatalf("interpolator.expand(): %q %s", s, err)
+		}
+		if v != want {
+			t.Fatalf("interpolator.expand(): %q %v", s, v)
+		}
+	}
+}
+
+func TestConfigurator_SetInterpolationNumbers(t *testing.T) {
+	defer setenv(t, map[string]string{"ZKITS_TEST_NAME": "app"})()
+
+	o := newInterpolationConfigurator(t)
+	item, err := o.Load("numbers.json")
+	if err != nil {
+		t.Fatalf("Configurator.Load(): %s", err)
+	}
+	// The json numbers are not converted to float64.
+	for _, want := range []string{`"name":"app"`, `"big":12345678901234567890`, `"id":9007199254740993`, `"ratio":0.1`, `[1e3,-0,42]`} {
+		if !strings.Contains(item.String(), want) {
+			t.Fatalf("Configurator.Load(): %s", item.String())
+		}
+	}
+}
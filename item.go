@@ -61,6 +61,48 @@ type Item interface {
 	YAML(interface{}) error
 }
 
+// FormatItem interface defines the config item that knows the format of its content.
+// All config items created by this package implement this interface, the custom
+// config items can be converted by the AsFormatItem function.
+type FormatItem interface {
+	Item
+
+	// Format returns the format name of the current config item content.
+	// If the format of the content cannot be determined, an empty string is returned.
+	Format() string
+
+	// Decode binds the current config item to the given object according to the
+	// format of the current config item.
+	// If the current configuration item is empty, ErrEmptyItem will be returned.
+	// If the format of the current configuration item is unknown, an UnknownFormatError
+	// will be returned.
+	Decode(interface{}) error
+
+	// Tree parses the current config item according to the format of the current
+	// config item and returns the config tree.
+	// If the current configuration item is empty, ErrEmptyItem will be returned.
+	// If the format of the current configuration item is unknown, an UnknownFormatError
+	// will be returned.
+	Tree() (Tree, error)
+}
+
+// AsFormatItem returns the given config item as a FormatItem.
+// If the given config item does not implement the FormatItem interface, such as the
+// config items of the custom loaders, a config item with the same content is created,
+// and the format is determined by content sniffing.
+func AsFormatItem(item Item) FormatItem {
+	if o, ok := item.(FormatItem); ok {
+		return o
+	}
+	return newBytesItem(item.Bytes())
+}
+
+// The decodeItem function binds the given config item to the given object according
+// to the format of the config item.
+func decodeItem(item Item, v interface{}) error {
+	return AsFormatItem(item).Decode(v)
+}
+
 // NewItemFromBytes creates and returns a config item from the given bytes.
 func NewItemFromBytes(data []byte) Item {
 	return newBytesItem(data)
@@ -80,14 +122,44 @@ func NewItemFromReader(r io.Reader) (Item, error) {
 	return newBytesItem(data), nil
 }
 
+// NewItemFromValue creates and returns a config item by encoding the given object
+// as the given format.
+// If the given format is not registered, an UnknownFormatError is returned.
+func NewItemFromValue(format string, v interface{}) (Item, error) {
+	data, err := encodeFormat(format, v)
+	if err != nil {
+		return nil, err
+	}
+	return newFormatItem(data, format), nil
+}
+
 // The newBytesItem function creates and returns a config item from the given bytes.
+// The format of the config item will be determined by content sniffing.
 func newBytesItem(data []byte) *bytesItem {
-	return &bytesItem{data}
+	return &bytesItem{data, sniffFormat(data)}
+}
+
+// The newFormatItem function creates and returns a config item from the given bytes
+// and the known format name.
+func newFormatItem(data []byte, format string) *bytesItem {
+	return &bytesItem{data, format}
+}
+
+// The withContent function returns a copy of the given config item with the
+// given content, the format of the config item and the config file information
+// are retained. The content no longer matches the config file, so the decoding
+// failures of the returned config file item do not report the positions.
+func withContent(item Item, data []byte) Item {
+	if o, ok := item.(*fileItem); ok {
+		return &fileItem{o.path, o.base, o.name, true, newFormatItem(data, o.format)}
+	}
+	return newFormatItem(data, AsFormatItem(item).Format())
 }
 
 // The bytesItem type is a built-in implementation of the Item interface.
 type bytesItem struct {
-	data []byte
+	data   []byte
+	format string
 }
 
 // IsEmpty determines whether the current config item content is empty.
@@ -121,7 +193,7 @@ func (item *bytesItem) JSON(o interface{}) error {
 	if len(item.data) == 0 {
 		return ErrEmptyItem
 	}
-	return json.Unmarshal(item.data, o)
+	return newConfigError(item.data, json.Unmarshal(item.data, o))
 }
 
 // XML binds the current config item to the given object as xml format.
@@ -130,7 +202,7 @@ func (item *bytesItem) XML(o interface{}) error {
 	if len(item.data) == 0 {
 		return ErrEmptyItem
 	}
-	return xml.Unmarshal(item.data, o)
+	return newConfigError(item.data, xml.Unmarshal(item.data, o))
 }
 
 // TOML binds the current config item to the given object as toml format.
@@ -139,7 +211,7 @@ func (item *bytesItem) TOML(o interface{}) error {
 	if len(item.data) == 0 {
 		return ErrEmptyItem
 	}
-	return toml.Unmarshal(item.data, o)
+	return newConfigError(item.data, toml.Unmarshal(item.data, o))
 }
 
 // YAML binds the current config item to the given object as yaml format.
@@ -148,7 +220,47 @@ func (item *bytesItem) YAML(o interface{}) error {
 	if len(item.data) == 0 {
 		return ErrEmptyItem
 	}
-	return yaml.Unmarshal(item.data, o)
+	return newConfigError(item.data, yaml.Unmarshal(item.data, o))
+}
+
+// Format returns the format name of the current config item content.
+// If the format of the content cannot be determined, an empty string is returned.
+func (item *bytesItem) Format() string {
+	return item.format
+}
+
+// Decode binds the current config item to the given object according to the
+// format of the current config item.
+// If the current configuration item is empty, ErrEmptyItem will be returned.
+// If the format of the current configuration item is unknown, an UnknownFormatError
+// will be returned.
+func (item *bytesItem) Decode(o interface{}) error {
+	if len(item.data) == 0 {
+		return ErrEmptyItem
+	}
+	if f := lookupFormat(item.format); f != nil {
+		return newConfigError(item.data, f.Unmarshal(item.data, o))
+	}
+	return &UnknownFormatError{Format: item.format}
+}
+
+// Tree parses the current config item according to the format of the current
+// config item and returns the config tree.
+// If the current configuration item is empty, ErrEmptyItem will be returned.
+// If the format of the current configuration item is unknown, an UnknownFormatError
+// will be returned.
+func (item *bytesItem) Tree() (Tree, error) {
+	return item.tree("")
+}
+
+// The tree method parses the current config item and returns the config tree
+// with the given source file path.
+func (item *bytesItem) tree(source string) (Tree, error) {
+	var m map[string]interface{}
+	if err := item.Decode(&m); err != nil {
+		return nil, err
+	}
+	return newTree(normalizeMap(m), "", source, item.format), nil
 }
 
 // FileItem interface defines the config file item.
@@ -189,11 +301,19 @@ func newFileItem(path string) (*fileItem, error) {
 	}
 
 	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	// If the format cannot be determined by the file ext name, we will try to
+	// sniff the file content.
+	format := formatByExt(ext)
+	if format == "" {
+		format = sniffFormat(data)
+	}
 	return &fileItem{
 		path,
 		base,
-		strings.TrimSuffix(base, filepath.Ext(base)),
-		newBytesItem(data),
+		strings.TrimSuffix(base, ext),
+		false,
+		newFormatItem(data, format),
 	}, nil
 }
 
@@ -203,6 +323,10 @@ type fileItem struct {
 	base string
 	name string
 
+	// The rewritten indicates whether the content has been rewritten, such as
+	// including and interpolation.
+	rewritten bool
+
 	*bytesItem
 }
 
@@ -220,3 +344,57 @@ func (item *fileItem) Base() string {
 func (item *fileItem) Name() string {
 	return item.name
 }
+
+// JSON binds the current config file item to the given object as json format.
+// The decoding failures are reported by a *ConfigError with the file path.
+func (item *fileItem) JSON(o interface{}) error {
+	return item.withPath(item.bytesItem.JSON(o))
+}
+
+// XML binds the current config file item to the given object as xml format.
+// The decoding failures are reported by a *ConfigError with the file path.
+func (item *fileItem) XML(o interface{}) error {
+	return item.withPath(item.bytesItem.XML(o))
+}
+
+// TOML binds the current config file item to the given object as toml format.
+// The decoding failures are reported by a *ConfigError with the file path.
+func (item *fileItem) TOML(o interface{}) error {
+	return item.withPath(item.bytesItem.TOML(o))
+}
+
+// YAML binds the current config file item to the given object as yaml format.
+// The decoding failures are reported by a *ConfigError with the file path.
+func (item *fileItem) YAML(o interface{}) error {
+	return item.withPath(item.bytesItem.YAML(o))
+}
+
+// Decode binds the current config file item to the given object according to
+// the format of the current config file item.
+// The decoding failures are reported by a *ConfigError with the file path.
+func (item *fileItem) Decode(o interface{}) error {
+	return item.withPath(item.bytesItem.Decode(o))
+}
+
+// The withPath method sets the file path of the given error if it is a *ConfigError.
+// If the content has been rewritten, the position of the failure is removed, since
+// it does not match the config file.
+func (item *fileItem) withPath(err error) error {
+	if e, ok := err.(*ConfigError); ok && e.Path == "" {
+		e.Path = item.path
+		if item.rewritten {
+			e.Line, e.Column, e.Snippet = 0, 0, ""
+		}
+	}
+	return err
+}
+
+// Tree parses the current config file item according to the format of the
+// current config file item and returns the config tree.
+// If the current configuration item is empty, ErrEmptyItem will be returned.
+// If the format of the current configuration item is unknown, an UnknownFormatError
+// will be returned.
+func (item *fileItem) Tree() (Tree, error) {
+	t, err := item.tree(item.path)
+	return t, item.withPath(err)
+}
@@ -16,6 +16,7 @@ package configurator
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -193,3 +194,115 @@ func TestFileItem(t *testing.T) {
 		t.Fatalf("FileItem.Name(): %s", got)
 	}
 }
+
+func TestBytesItem_Format(t *testing.T) {
+	items := map[string]string{
+		"":                              "",
+		"test":                          "",
+		`{"name":"test"}`:               FormatJSON,
+		`<xml><name>test</name></xml>`:  FormatXML,
+		`name = 'test'`:                 FormatTOML,
+		"[server]\nport = 80":           FormatTOML,
+		`name: 'test'`:                  FormatYAML,
+		"<xml><name>test</name></yaml>": "",
+	}
+	for s, want := range items {
+		if got := AsFormatItem(NewItemFromString(s)).Format(); got != want {
+			t.Fatalf("Item.Format(): %q => %q", s, got)
+		}
+	}
+}
+
+func TestBytesItem_Decode(t *testing.T) {
+	type Value struct {
+		Name string `json:"name" xml:"name" toml:"name" yaml:"name"`
+	}
+
+	items := []string{
+		`{"name":"test"}`,
+		`<xml><name>test</name></xml>`,
+		`name = 'test'`,
+		`name: 'test'`,
+	}
+	for _, s := range items {
+		v := new(Value)
+		if err := AsFormatItem(NewItemFromString(s)).Decode(v); err != nil {
+			t.Fatalf("Item.Decode(): %s", err)
+		}
+		if v.Name != "test" {
+			t.Fatalf("Item.Decode(): %s", v.Name)
+		}
+	}
+
+	if err := AsFormatItem(NewItemFromString("")).Decode(new(Value)); err != ErrEmptyItem {
+		t.Fatalf("Item.Decode(): %v", err)
+	}
+	if err := AsFormatItem(NewItemFromString("test")).Decode(new(Value)); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Item.Decode(): %v", err)
+	}
+}
+
+func TestFileItem_Decode(t *testing.T) {
+	type Value struct {
+		Name string `json:"name" xml:"name" toml:"name" yaml:"name"`
+	}
+
+	formats := map[string]string{
+		"test/test.json": FormatJSON,
+		"test/test.xml":  FormatXML,
+		"test/test.toml": FormatTOML,
+		"test/test.yaml": FormatYAML,
+	}
+	for path, format := range formats {
+		item, err := NewFileItem(path)
+		if err != nil {
+			t.Fatalf("NewFileItem(): %s", err)
+		}
+		if got := AsFormatItem(item).Format(); got != format {
+			t.Fatalf("FileItem.Format(): %s", got)
+		}
+		v := new(Value)
+		if err := AsFormatItem(item).Decode(v); err != nil {
+			t.Fatalf("FileItem.Decode(): %s", err)
+		}
+		if v.Name != "test" {
+			t.Fatalf("FileItem.Decode(): %s", v.Name)
+		}
+	}
+
+	item, err := NewFileItem("test/test.txt")
+	if err != nil {
+		t.Fatalf("NewFileItem(): %s", err)
+	}
+	err = AsFormatItem(item).Decode(new(Value))
+	if e := new(UnknownFormatError); !errors.As(err, &e) {
+		t.Fatalf("FileItem.Decode(): %v", err)
+	}
+}
+
+func TestAsFormatItem(t *testing.T) {
+	item := NewItemFromString(`{"name": "a"}`)
+	if AsFormatItem(item) != item {
+		t.Fatal("AsFormatItem(): not the same item")
+	}
+
+	// The custom config item does not implement the FormatItem interface.
+	custom := struct{ Item }{item}
+	if _, ok := Item(custom).(FormatItem); ok {
+		t.Fatal("AsFormatItem(): custom item implements FormatItem")
+	}
+	if got := AsFormatItem(custom).Format(); got != FormatJSON {
+		t.Fatalf("AsFormatItem(): %q", got)
+	}
+
+	o := New().Use(LoaderFunc(func(target string) (Item, error) {
+		return custom, nil
+	}))
+	if tree, err := o.LoadTree("a"); err != nil || tree.GetString("name") != "a" {
+		t.Fatalf("Configurator.LoadTree(): %v", err)
+	}
+	var v struct{ Name string }
+	if err := o.LoadInto("a", &v); err != nil || v.Name != "a" {
+		t.Fatalf("Configurator.LoadInto(): %v", err)
+	}
+}
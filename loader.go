@@ -17,16 +17,24 @@ package configurator
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Loader interface defines the config target loader.
+// Note that the Configurator.Load method returns the config item of the first loader
+// that finds the config target, so a loader registered by the Configurator.Use method,
+// such as the environment variable loader or the flag loader, replaces the whole
+// config target of the other loaders. To override only the given keys, use the
+// Configurator.LoadMerged method or the loader created by the NewMergeLoader function.
 type Loader interface {
 	// Load loads the given config target.
 	// If this method returns a non-nil error, the entire configuration search and
 	// loading process will be terminated immediately. If the returned Item is nil,
-	// the next loader in the queue will be automatically run.
+	// the next loader in the queue will be automatically run. The FileLoader,
+	// EnvLoader and FlagLoader return nil Item if the config target is not found.
 	Load(string) (Item, error)
 }
 
@@ -43,14 +51,81 @@ type FileLoader interface {
 	Loader
 
 	// AddFile adds one or more config files to the current loader.
-	// The given parameter need to comply with the search rules supported
-	// by filepath.Glob.
-	AddFile(string) error
+	// The given patterns need to comply with the search rules supported by
+	// filepath.Glob, in addition, the "**" segments match zero or more directories,
+	// such as "conf/**/*.yaml", and the brace alternatives are supported, such as
+	// "*.{yml,yaml}". The patterns prefixed with "!" are the exclude patterns, such
+	// as "!**/*.example.yaml", they exclude the matched config files from all the
+	// added patterns of the current loader. The exclude patterns without slashes
+	// match the base names of the config files.
+	AddFile(...string) error
 
 	// MustAddFile adds one or more config files to the current loader.
 	// This method is very similar to AddFile, the only difference is that it panics
 	// when the add fails.
-	MustAddFile(pattern string) FileLoader
+	MustAddFile(patterns ...string) FileLoader
+
+	// AddIgnoreFile reads the given .gitignore-style ignore file, the config files
+	// matched by the rules of the ignore file are ignored by the current loader.
+	// The rules are relative to the directory of the ignore file, and the rules
+	// prefixed with "!" include the matched config files again.
+	AddIgnoreFile(string) error
+
+	// Rescan resolves all the added patterns again, so the config files created after
+	// adding can be loaded, and the deleted config files are no longer registered.
+	Rescan()
+
+	// SetAutoRescan sets the interval of the automatic rescanning. If the interval is
+	// positive, a missed config target triggers rescanning (at most once in the given
+	// interval) and the config target is looked up again. The automatic rescanning is
+	// disabled by default.
+	SetAutoRescan(time.Duration) FileLoader
+
+	// RemoveFile removes the given pattern added by the AddFile or AddDir method, the
+	// patterns added by the AddDir method are the joined paths, such as "root/*/*".
+	// The exclude patterns prefixed with "!" can also be removed. If the given pattern
+	// has not been added, false is returned.
+	RemoveFile(string) bool
+
+	// Reset removes all the added patterns, exclude patterns and ignore rules.
+	Reset()
+
+	// Files returns all the registered config files sorted by config target.
+	Files() []FileEntry
+
+	// Targets returns the unique names of the registered config targets in order.
+	Targets() []string
+
+	// SetFormatOrder sets the format preference order of the config files of the same
+	// config target, such as "yaml", "toml" and "json". When a config target without
+	// ext name matches multiple config files, the config file of the preferred format
+	// is loaded. The formats not in the order rank last, and the latest registered
+	// config file wins among the config files of the same preference.
+	SetFormatOrder(...string) FileLoader
+
+	// SetAmbiguityError sets whether to return an *AmbiguousTargetError when a config
+	// target without ext name matches multiple config files that are not distinguished
+	// by the format preference order.
+	SetAmbiguityError(bool) FileLoader
+
+	// Candidates returns the config files matched by the given config target in
+	// priority order, the first one is loaded and the others are shadowed.
+	Candidates(string) []FileEntry
+
+	// AddDir adds the config files that match the given pattern under the given root
+	// directory to the current loader. Unlike AddFile, the config targets of the
+	// config files are the slash-separated relative paths without the ext names,
+	// such as "foo/a" for "root/foo/a.json". The short names, such as "a", can also
+	// be used if they are unambiguous, otherwise an *AmbiguousTargetError is returned.
+	AddDir(root, pattern string) error
+
+	// Watch watches the config files and the added patterns of the current loader.
+	// The given function is called for every detected change. The changes are detected
+	// by polling with the given interval, and on Linux, inotify is also used to detect
+	// the changes immediately. For the patterns containing "**", all the subdirectories
+	// are watched, including the ones created later. If the given interval is not
+	// positive, DefaultWatchInterval is used.
+	Watch(time.Duration, WatchFunc) (Watcher, error)
 }
 
 // NewFileLoader creates and returns a config file loader instance.
@@ -65,24 +140,443 @@ func newFileLoader() *fileLoader {
 
 // The fileLoader type is a built-in implementation of the FileLoader interface.
 type fileLoader struct {
-	mutex sync.RWMutex
-	files map[string][][4]string
+	mutex    sync.RWMutex
+	files    map[string][][4]string
+	aliases  map[string]map[string]bool
+	patterns []filePattern
+	excludes []string
+	ignores  []ignoreRule
+	version  int
+	interval time.Duration
+	scanned  time.Time
+	// The formats are the format preference order of the config files.
+	formats   []string
+	ambiguity bool
+	// The states are the config file states of the last scanning, and the hooks
+	// are called for the config file changes detected by scanning.
+	states map[string]fileState
+	hooks  []func(Event)
+	// The matches are the config files matched by the added patterns in the last
+	// scanning, the new patterns are resolved alone when adding.
+	matches map[filePattern][]matchedFile
+}
+
+// FileEntry type defines the registered config file of the FileLoader.
+type FileEntry struct {
+	// Target is the name of the config target, such as "name" and "foo/name".
+	Target string
+
+	// Ext is the ext name of the config file, such as ".json".
+	Ext string
+
+	// Path is the absolute path of the config file.
+	Path string
+}
+
+// The filePattern type defines the added pattern of the config files.
+// If the root is not empty, the config files are added by the AddDir method.
+type filePattern struct {
+	root    string
+	pattern string
+}
+
+// The glob method returns the full glob pattern of the current pattern.
+func (p filePattern) glob() string {
+	if p.root == "" {
+		return p.pattern
+	}
+	return filepath.Join(p.root, p.pattern)
+}
+
+// The match method returns the regular files that match the current pattern.
+// The names of the files under the root directory are the relative paths.
+func (p filePattern) match(skip bool) ([][4]string, error) {
+	list, err := matchFiles(p.glob(), skip)
+	if err != nil || p.root == "" {
+		return list, err
+	}
+	for i, j := 0, len(list); i < j; i++ {
+		rel, err := filepath.Rel(p.root, list[i][3])
+		if err != nil {
+			return nil, err
+		}
+		list[i][1] = filepath.ToSlash(strings.TrimSuffix(rel, list[i][0]))
+	}
+	return list, nil
+}
+
+// The matchedFile type is a config file matched by the added pattern.
+type matchedFile struct {
+	// The entry is [ext, name, base, path] of the config file.
+	entry [4]string
+	// The path is the absolute path of the config file, and the info is the
+	// file info of the config file, it is nil if the config file cannot be accessed.
+	path string
+	info os.FileInfo
+}
+
+// The scan method returns the regular files that match the current pattern with
+// the file info. If skip is true, the files that cannot be accessed are ignored.
+func (p filePattern) scan(skip bool) ([]matchedFile, error) {
+	list, err := p.match(skip)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]matchedFile, len(list))
+	for i, j := 0, len(list); i < j; i++ {
+		r[i].entry = list[i]
+		r[i].path, _ = filepath.Abs(list[i][3])
+		if info, err := os.Stat(list[i][3]); err == nil {
+			r[i].info = info
+		}
+	}
+	return r, nil
 }
 
 // AddFile adds one or more config files to the current loader.
-// The given parameter need to comply with the search rules supported
-// by filepath.Glob.
-func (o *fileLoader) AddFile(pattern string) error {
-	matches, err := filepath.Glob(pattern)
-	if err != nil || len(matches) == 0 {
+// The given patterns need to comply with the search rules supported by
+// filepath.Glob, the "**" segments and the brace alternatives are also supported.
+// The patterns prefixed with "!" are the exclude patterns.
+func (o *fileLoader) AddFile(patterns ...string) error {
+	var includes []filePattern
+	var excludes []string
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") {
+			if err := checkPattern(pattern[1:]); err != nil {
+				return err
+			}
+			excludes = append(excludes, pattern[1:])
+			continue
+		}
+		includes = append(includes, filePattern{pattern: pattern})
+	}
+	return o.add(includes, excludes, nil)
+}
+
+// AddIgnoreFile reads the given .gitignore-style ignore file, the config files
+// matched by the rules of the ignore file are ignored by the current loader.
+func (o *fileLoader) AddIgnoreFile(name string) error {
+	rules, err := readIgnoreFile(name)
+	if err != nil {
 		return err
 	}
+	return o.add(nil, nil, rules)
+}
+
+// AddDir adds the config files that match the given pattern under the given root
+// directory to the current loader, the config targets are the relative paths.
+func (o *fileLoader) AddDir(root, pattern string) error {
+	if root == "" {
+		root = "."
+	}
+	return o.add([]filePattern{{root: filepath.Clean(root), pattern: pattern}}, nil, nil)
+}
+
+// The add method adds the given patterns, exclude patterns and ignore rules, and
+// rebuilds the registered config files.
+func (o *fileLoader) add(patterns []filePattern, excludes []string, rules []ignoreRule) error {
+	matches := make(map[filePattern][]matchedFile, len(patterns))
+	for _, p := range patterns {
+		// The inaccessible config files are reported when adding.
+		found, err := p.scan(false)
+		if err != nil {
+			return err
+		}
+		matches[p] = found
+	}
+
+	o.mutex.Lock()
+	// The patterns are remembered so that the watchers can find the config files
+	// that newly match the patterns.
+	o.patterns = append(o.patterns, patterns...)
+	o.excludes = append(o.excludes, excludes...)
+	o.ignores = append(o.ignores, rules...)
+	if o.matches == nil {
+		o.matches = make(map[filePattern][]matchedFile)
+	}
+	for p, found := range matches {
+		o.matches[p] = found
+	}
+	o.version++
+	o.mutex.Unlock()
+
+	// Only the given patterns are resolved, the exclude patterns and the ignore
+	// rules also apply to the config files of the previously added patterns.
+	o.update(false)
+	return nil
+}
+
+// RemoveFile removes the given pattern added by the AddFile or AddDir method.
+// If the given pattern has not been added, false is returned.
+func (o *fileLoader) RemoveFile(pattern string) bool {
+	o.mutex.Lock()
+	var removed bool
+	if strings.HasPrefix(pattern, "!") {
+		var excludes []string
+		for _, exclude := range o.excludes {
+			if exclude == pattern[1:] {
+				removed = true
+				continue
+			}
+			excludes = append(excludes, exclude)
+		}
+		o.excludes = excludes
+	} else {
+		var patterns []filePattern
+		for _, p := range o.patterns {
+			if filepath.Clean(p.glob()) == filepath.Clean(pattern) {
+				removed = true
+				continue
+			}
+			patterns = append(patterns, p)
+		}
+		o.patterns = patterns
+	}
+	if removed {
+		o.version++
+	}
+	o.mutex.Unlock()
+
+	if removed {
+		o.rescan()
+	}
+	return removed
+}
+
+// Reset removes all the added patterns, exclude patterns and ignore rules.
+// The interval of the automatic rescanning is kept.
+func (o *fileLoader) Reset() {
+	o.mutex.Lock()
+	o.patterns, o.excludes, o.ignores = nil, nil, nil
+	o.version++
+	o.mutex.Unlock()
+
+	// The removed config files are reported to the hooks by rescanning.
+	o.rescan()
+}
+
+// Files returns all the registered config files sorted by config target.
+// The config files of the same config target are sorted by priority, the first
+// one is loaded by the config target without ext name.
+func (o *fileLoader) Files() []FileEntry {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	var r []FileEntry
+	for _, name := range o.sortedNames() {
+		r = append(r, fileEntries(o.order(o.files[name]))...)
+	}
+	return r
+}
+
+// The fileEntries function converts the given registered config files to entries.
+func fileEntries(files [][4]string) []FileEntry {
+	r := make([]FileEntry, len(files))
+	for i, j := 0, len(files); i < j; i++ {
+		path, err := filepath.Abs(files[i][3])
+		if err != nil {
+			path = files[i][3]
+		}
+		r[i] = FileEntry{Target: files[i][1], Ext: files[i][0], Path: path}
+	}
+	return r
+}
+
+// Targets returns the unique names of the registered config targets in order.
+func (o *fileLoader) Targets() []string {
+	return o.names()
+}
+
+// The names method returns the sorted names of the registered config files.
+func (o *fileLoader) names() []string {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	return o.sortedNames()
+}
+
+// The sortedNames method returns the sorted names of the registered config files.
+func (o *fileLoader) sortedNames() []string {
+	names := make([]string, 0, len(o.files))
+	for name := range o.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The filterFiles function removes the matched files excluded by the given exclude
+// patterns and ignore rules.
+func filterFiles(list [][4]string, excludes []string, rules []ignoreRule) [][4]string {
+	if len(excludes) == 0 && len(rules) == 0 {
+		return list
+	}
+	var r [][4]string
+	for i, j := 0, len(list); i < j; i++ {
+		if excluded(excludes, rules, list[i][3]) {
+			continue
+		}
+		r = append(r, list[i])
+	}
+	return r
+}
+
+// The excluded function determines whether the given file path is excluded by the
+// given exclude patterns or ignore rules.
+func excluded(excludes []string, rules []ignoreRule, path string) bool {
+	for _, pattern := range excludes {
+		if matchExclude(pattern, path) {
+			return true
+		}
+	}
+	if len(rules) > 0 {
+		if abs, err := filepath.Abs(path); err == nil {
+			return ignored(rules, abs)
+		}
+	}
+	return false
+}
+
+// The addFiles function adds the given matched files to the given maps.
+// The short names of the files under the root directories are the aliases.
+func addFiles(files map[string][][4]string, aliases map[string]map[string]bool, p filePattern, list [][4]string) {
+	for i, j := 0, len(list); i < j; i++ {
+		name := list[i][1]
+		files[name] = append(files[name], list[i])
+		if p.root != "" {
+			short := strings.TrimSuffix(list[i][2], list[i][0])
+			if aliases[short] == nil {
+				aliases[short] = make(map[string]bool)
+			}
+			aliases[short][name] = true
+		}
+	}
+}
+
+// The targetNames function returns the given config target and its short name,
+// such as "foo/a" and "a" for the config files added by the AddDir method.
+// The short name is derived from the config target, so it is also returned for
+// the removed config files.
+func targetNames(target string) []string {
+	if i := strings.LastIndexByte(target, '/'); i >= 0 && i < len(target)-1 {
+		return []string{target, target[i+1:]}
+	}
+	return []string{target}
+}
+
+// AmbiguousTargetError reports that the short name of the config target matches
+// multiple config files added by the AddDir method, or the config target without
+// ext name matches multiple config files when the ambiguity error is enabled.
+type AmbiguousTargetError struct {
+	// Target is the ambiguous config target.
+	Target string
+
+	// Candidates are the full config targets matched by the short name, such as
+	// "foo/a" and "bar/a", or the paths of the config files matched by the config
+	// target without ext name.
+	Candidates []string
+}
+
+// Error returns the error message.
+func (e *AmbiguousTargetError) Error() string {
+	return "configurator: ambiguous target \"" + e.Target + "\" matches " + strings.Join(e.Candidates, ", ")
+}
+
+// Is determines whether the current error matches the given error.
+// All AmbiguousTargetError instances match ErrAmbiguousTarget.
+func (e *AmbiguousTargetError) Is(err error) bool {
+	return err == ErrAmbiguousTarget
+}
+
+// The rescan method resolves all the added patterns again, rebuilds the registered
+// config files and returns the states of the registered config files.
+func (o *fileLoader) rescan() map[string]fileState {
+	return o.update(true)
+}
+
+// The update method rebuilds the registered config files and returns the states
+// of the registered config files. If full is false, only the patterns that have
+// not been resolved are resolved, the others use the results of the last scanning.
+func (o *fileLoader) update(full bool) map[string]fileState {
+	for {
+		o.mutex.RLock()
+		version, patterns, excludes, rules := o.version, o.patterns, o.excludes, o.ignores
+		last := o.matches
+		o.mutex.RUnlock()
+
+		matches := make(map[filePattern][]matchedFile, len(patterns))
+		files := make(map[string][][4]string)
+		aliases := make(map[string]map[string]bool)
+		states := make(map[string]fileState)
+		for _, pattern := range patterns {
+			found, ok := matches[pattern]
+			if !ok {
+				if found, ok = last[pattern]; !ok || full {
+					found, _ = pattern.scan(true)
+				}
+				matches[pattern] = found
+			}
+			list := make([][4]string, len(found))
+			infos := make(map[string]matchedFile, len(found))
+			for i, f := range found {
+				list[i], infos[f.entry[3]] = f.entry, f
+			}
+			list = filterFiles(list, excludes, rules)
+			addFiles(files, aliases, pattern, list)
+			for i, j := 0, len(list); i < j; i++ {
+				if f := infos[list[i][3]]; f.info != nil {
+					states[f.path] = fileState{target: list[i][1], info: f.info}
+				}
+			}
+		}
+
+		o.mutex.Lock()
+		// If the patterns are changed during scanning, we need to scan again.
+		if o.version == version {
+			old, hooks := o.states, o.hooks
+			o.files, o.aliases, o.states, o.matches = files, aliases, states, matches
+			if full {
+				o.scanned = time.Now()
+			}
+			o.mutex.Unlock()
+			if len(hooks) > 0 {
+				for _, e := range diffStates(old, states) {
+					for _, fn := range hooks {
+						fn(e)
+					}
+				}
+			}
+			return states
+		}
+		o.mutex.Unlock()
+	}
+}
+
+// The onRescan method adds the hook that is called for every config file change
+// detected by scanning, such as a config file created or deleted since the last
+// scanning.
+func (o *fileLoader) onRescan(fn func(Event)) {
+	o.mutex.Lock()
+	o.hooks = append(o.hooks, fn)
+	o.mutex.Unlock()
+}
+
+// The matchFiles function returns the regular files that match the given pattern.
+// Each element of the result is [ext, name, base, path].
+// If skip is true, the files that cannot be accessed are ignored.
+func matchFiles(pattern string, skip bool) ([][4]string, error) {
+	matches, err := globFiles(pattern)
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
 
 	var list [][4]string
 	for i, j := 0, len(matches); i < j; i++ {
 		info, err := os.Stat(matches[i])
 		if err != nil {
-			return err
+			if skip {
+				continue
+			}
+			return nil, err
 		}
 		// We only care about regular files.
 		if info.Mode().IsRegular() {
@@ -91,70 +585,255 @@ func (o *fileLoader) AddFile(pattern string) error {
 			list = append(list, [4]string{e, strings.TrimSuffix(b, e), b, matches[i]})
 		}
 	}
-
-	if len(list) > 0 {
-		o.mutex.Lock()
-		if o.files == nil {
-			o.files = make(map[string][][4]string)
-		}
-		for i, j := 0, len(list); i < j; i++ {
-			o.files[list[i][1]] = append(o.files[list[i][1]], list[i])
-		}
-		o.mutex.Unlock()
-	}
-	return nil
+	return list, nil
 }
 
 // MustAddFile adds one or more config files to the current loader.
 // This method is very similar to AddFile, the only difference is that it panics
 // when the add fails.
-func (o *fileLoader) MustAddFile(pattern string) FileLoader {
-	if err := o.AddFile(pattern); err != nil {
+func (o *fileLoader) MustAddFile(patterns ...string) FileLoader {
+	if err := o.AddFile(patterns...); err != nil {
 		panic(err)
 	}
 	return o
 }
 
+// Rescan resolves all the added patterns again, so the config files created after
+// adding can be loaded, and the deleted config files are no longer registered.
+func (o *fileLoader) Rescan() {
+	o.rescan()
+}
+
+// SetAutoRescan sets the interval of the automatic rescanning.
+// If the given interval is not positive, the automatic rescanning is disabled.
+func (o *fileLoader) SetAutoRescan(interval time.Duration) FileLoader {
+	o.mutex.Lock()
+	o.interval = interval
+	o.mutex.Unlock()
+	return o
+}
+
+// SetFormatOrder sets the format preference order of the config files of the same
+// config target, the given formats are the registered format names.
+func (o *fileLoader) SetFormatOrder(formats ...string) FileLoader {
+	o.mutex.Lock()
+	o.formats = append([]string(nil), formats...)
+	o.mutex.Unlock()
+	return o
+}
+
+// SetAmbiguityError sets whether to return an *AmbiguousTargetError when a config
+// target without ext name matches multiple config files of the same preference.
+func (o *fileLoader) SetAmbiguityError(enabled bool) FileLoader {
+	o.mutex.Lock()
+	o.ambiguity = enabled
+	o.mutex.Unlock()
+	return o
+}
+
+// Candidates returns the config files matched by the given config target in
+// priority order, the first one is loaded and the others are shadowed.
+// For the short names of the config files added by the AddDir method, the config
+// files of all the full config targets are returned in order of config target.
+func (o *fileLoader) Candidates(target string) []FileEntry {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+
+	if list, _ := o.candidates(target); len(list) > 0 {
+		return fileEntries(list)
+	}
+	names := o.expand(target)
+	sort.Strings(names)
+	var r []FileEntry
+	for _, name := range names {
+		list, _ := o.candidates(name)
+		r = append(r, fileEntries(list)...)
+	}
+	return r
+}
+
+// The rescanDue method determines whether the missed config target should trigger
+// the automatic rescanning.
+func (o *fileLoader) rescanDue() bool {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	return o.interval > 0 && time.Since(o.scanned) >= o.interval
+}
+
 // Load loads the given config file target.
 // If the given config file does not exist, nil Item is returned.
+// If the given config target is an ambiguous short name of the config files added
+// by the AddDir method, an *AmbiguousTargetError is returned.
 func (o *fileLoader) Load(target string) (Item, error) {
+	item, err := o.load(target)
+	if item == nil && err == nil && o.rescanDue() {
+		// The config file may be created or deleted after the last scanning.
+		o.rescan()
+		return o.load(target)
+	}
+	return item, err
+}
+
+// The load method loads the config file of the given config target.
+// The deleted config files are treated as not found.
+func (o *fileLoader) load(target string) (Item, error) {
+	path, err := o.lookup(target)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	item, err := newFileItem(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// The lookup method returns the path of the config file of the given config target.
+// If the given config file does not exist, an empty string is returned.
+func (o *fileLoader) lookup(target string) (string, error) {
 	o.mutex.RLock()
 	defer o.mutex.RUnlock()
 	if len(o.files) == 0 {
-		return nil, nil
+		return "", nil
 	}
 
-	// If the config target already exists, the ext name will not be split and
-	// the latest config file will be returned directly.
-	// For example: Given "name.suffix", returns "/path/to/name.suffix.json".
-	if a := o.files[target]; len(a) > 0 {
-		item, err := newFileItem(a[len(a)-1][3])
+	path, err := o.find(target)
+	if path != "" || err != nil {
+		return path, err
+	}
+	// The short names of the config files added by the AddDir method are used
+	// only if they are unambiguous.
+	var candidates []string
+	for _, name := range o.expand(target) {
+		p, err := o.find(name)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
-		return item, nil
+		if p != "" {
+			candidates = append(candidates, name)
+			path = p
+		}
+	}
+	if len(candidates) > 1 {
+		sort.Strings(candidates)
+		return "", &AmbiguousTargetError{Target: target, Candidates: candidates}
 	}
+	return path, nil
+}
 
+// The expand method returns the full config targets of the given short name of
+// the config files added by the AddDir method, the ext name is kept.
+// For example: Given "a.json", returns "foo/a.json" and "bar/a.json".
+func (o *fileLoader) expand(target string) []string {
+	var r []string
+	if names := o.aliases[target]; len(names) > 0 {
+		for name := range names {
+			r = append(r, name)
+		}
+		return r
+	}
 	e := filepath.Ext(target)
-	// If there is no ext name, it can be determined that the target does not exist.
 	if e == "" {
-		return nil, nil
+		return nil
 	}
+	for name := range o.aliases[strings.TrimSuffix(target, e)] {
+		r = append(r, name+e)
+	}
+	return r
+}
+
+// The find method returns the path of the config file of the given config target.
+// If the given config file does not exist, an empty string is returned.
+// If the ambiguity error is enabled and the given config target without ext name
+// matches multiple config files of the same preference, an *AmbiguousTargetError
+// is returned.
+func (o *fileLoader) find(target string) (string, error) {
+	list, exact := o.candidates(target)
+	if len(list) == 0 {
+		return "", nil
+	}
+	if exact && o.ambiguity && len(list) > 1 && o.rank(list[0][0]) == o.rank(list[1][0]) {
+		var paths []string
+		for i, j := 0, len(list); i < j && o.rank(list[i][0]) == o.rank(list[0][0]); i++ {
+			paths = append(paths, list[i][3])
+		}
+		sort.Strings(paths)
+		return "", &AmbiguousTargetError{Target: target, Candidates: paths}
+	}
+	return list[0][3], nil
+}
 
-	n := strings.TrimSuffix(target, e)
-	if len(o.files[n]) == 0 {
-		return nil, nil
+// The candidates method returns the config files of the given config target in
+// priority order, the first one is loaded and the others are shadowed.
+// If the config files are matched by the full name of the given config target,
+// the exact is true.
+func (o *fileLoader) candidates(target string) (list [][4]string, exact bool) {
+	// If the config target already exists, the ext name will not be split, the
+	// config files are ordered by the format preference and the latest config file
+	// of the same preference will be returned.
+	// For example: Given "name.suffix", returns "/path/to/name.suffix.json".
+	if a := o.files[target]; len(a) > 0 {
+		return o.order(a), true
+	}
+
+	e := filepath.Ext(target)
+	// If there is no ext name, it can be determined that the target does not exist.
+	if e == "" {
+		return nil, false
 	}
 
-	r := o.files[n]
-	for i := len(r) - 1; i >= 0; i-- {
+	r := o.order(o.files[strings.TrimSuffix(target, e)])
+	for i, j := 0, len(r); i < j; i++ {
 		if r[i][0] == e {
-			item, err := newFileItem(r[i][3])
-			if err != nil {
-				return nil, err
+			list = append(list, r[i])
+		}
+	}
+	// If there is no config file with the given ext name, the config files of
+	// the same format are also acceptable.
+	// For example: Given "name.yml", returns "/path/to/name.yaml".
+	if f := formatByExt(e); f != "" {
+		for i, j := 0, len(r); i < j; i++ {
+			if r[i][0] != e && formatByExt(r[i][0]) == f {
+				list = append(list, r[i])
+			}
+		}
+	}
+	return list, false
+}
+
+// The order method returns the given registered config files sorted by priority,
+// the config files of the preferred formats come first, and the config files of the
+// same preference are sorted by registration order from latest to earliest.
+// The duplicate config files are removed.
+func (o *fileLoader) order(files [][4]string) [][4]string {
+	var r [][4]string
+	seen := make(map[string]bool, len(files))
+	for i := len(files) - 1; i >= 0; i-- {
+		if !seen[files[i][3]] {
+			seen[files[i][3]] = true
+			r = append(r, files[i])
+		}
+	}
+	if len(o.formats) > 0 {
+		sort.SliceStable(r, func(i, j int) bool {
+			return o.rank(r[i][0]) < o.rank(r[j][0])
+		})
+	}
+	return r
+}
+
+// The rank method returns the preference of the given ext name, the smaller is
+// preferred. The formats that are not in the preference order rank last.
+func (o *fileLoader) rank(ext string) int {
+	if f := formatByExt(ext); f != "" {
+		for i, j := 0, len(o.formats); i < j; i++ {
+			if o.formats[i] == f {
+				return i
 			}
-			return item, nil
 		}
 	}
-	return nil, nil
+	return len(o.formats)
 }
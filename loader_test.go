@@ -0,0 +1,551 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileLoader_AddDir(t *testing.T) {
+	l := newFileLoader()
+	if err := l.AddDir("test/dirs", "*/*"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddDir("test/dirs/", "*/*/*"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddDir("test/dirs", "["); err == nil {
+		t.Fatal("FileLoader.AddDir(): no error")
+	}
+
+	items := map[string]string{
+		"foo/a":      "test/dirs/foo/a.json",
+		"foo/a.json": "test/dirs/foo/a.json",
+		"bar/a.yml":  "test/dirs/bar/a.yaml",
+		"bar/sub/c":  "test/dirs/bar/sub/c.toml",
+		"b":          "test/dirs/foo/b.json",
+		"c.toml":     "test/dirs/bar/sub/c.toml",
+		// Only one of the config files of the short name has the ext name.
+		"a.json": "test/dirs/foo/a.json",
+		"a.yaml": "test/dirs/bar/a.yaml",
+	}
+	for target, path := range items {
+		item, err := l.Load(target)
+		if err != nil {
+			t.Fatalf("FileLoader.Load(): %s %s", target, err)
+		}
+		if item == nil || item.(FileItem).Path() != path {
+			t.Fatalf("FileLoader.Load(): %s %v", target, item)
+		}
+	}
+	for _, target := range []string{"sub/c", "d", "foo/d.json", "a.toml"} {
+		if item, err := l.Load(target); item != nil || err != nil {
+			t.Fatalf("FileLoader.Load(): %s %v %v", target, item, err)
+		}
+	}
+
+	_, err := l.Load("a")
+	var e *AmbiguousTargetError
+	if !errors.As(err, &e) || !errors.Is(err, ErrAmbiguousTarget) || !reflect.DeepEqual(e.Candidates, []string{"bar/a", "foo/a"}) {
+		t.Fatalf("FileLoader.Load(): %v", err)
+	}
+	if err.Error() != `configurator: ambiguous target "a" matches bar/a, foo/a` {
+		t.Fatalf("FileLoader.Load(): %s", err)
+	}
+
+	names := l.names()
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"bar/a", "bar/sub/c", "foo/a", "foo/b"}) {
+		t.Fatalf("FileLoader.names(): %v", names)
+	}
+	states := l.rescan()
+	path, _ := filepath.Abs("test/dirs/bar/sub/c.toml")
+	if states[path].target != "bar/sub/c" {
+		t.Fatalf("FileLoader.rescan(): %v", states)
+	}
+	if _, err := l.Load("a"); !errors.Is(err, ErrAmbiguousTarget) {
+		t.Fatalf("FileLoader.Load(): %v", err)
+	}
+}
+
+func TestConfigurator_AddDir(t *testing.T) {
+	o := New()
+	if err := o.AddDir("test/dirs", "*/*"); err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		Name string `json:"name" yaml:"name"`
+	}
+	if err := o.LoadYAML("bar/a", &v); err != nil || v.Name != "bar/a" {
+		t.Fatalf("Configurator.LoadYAML(): %v %s", err, v.Name)
+	}
+	if err := o.LoadJSON("a", &v); !errors.Is(err, ErrAmbiguousTarget) {
+		t.Fatalf("Configurator.LoadJSON(): %v", err)
+	}
+	var e *AmbiguousTargetError
+	if _, err := o.Load("a"); !errors.As(err, &e) || e.Target != "a" {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+
+	// The config files added by the AddFile method take precedence over the short names.
+	if err := o.AddFile("test/dirs/foo/*"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.LoadJSON("a", &v); err != nil || v.Name != "foo/a" {
+		t.Fatalf("Configurator.LoadJSON(): %v %s", err, v.Name)
+	}
+}
+
+func TestFileLoader_AddFile(t *testing.T) {
+	l := newFileLoader()
+	if err := l.AddFile("test/glob/**/*.{yml,yaml}", "!**/*.example.{yml,yaml}", "!**/secret/*"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddFile("!["); err == nil {
+		t.Fatal("FileLoader.AddFile(): no error")
+	}
+	names := l.names()
+	sort.Strings(names)
+	want := []string{"app", "db", "db.local", "keep.local"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("FileLoader.names(): %v", names)
+	}
+
+	// The exclude patterns also apply to the previously added patterns.
+	if err := l.AddFile("test/glob/conf/cache/*", "!*.local.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	names = l.names()
+	sort.Strings(names)
+	want = []string{"app", "cache", "db"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("FileLoader.names(): %v", names)
+	}
+}
+
+func TestConfigurator_AddIgnoreFile(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/glob/conf/**/*", "!**/.configignore"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.AddIgnoreFile("test/glob/conf/.configignore"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.AddIgnoreFile("test/glob/conf/.notfound"); err == nil {
+		t.Fatal("Configurator.AddIgnoreFile(): no error")
+	}
+	var v struct {
+		Name string `json:"name" yaml:"name"`
+	}
+	for _, target := range []string{"app", "app.example", "db", "keep.local", "cache"} {
+		if err := o.LoadYAML(target, &v); err != nil || v.Name != target {
+			t.Fatalf("Configurator.LoadYAML(): %s %v %s", target, err, v.Name)
+		}
+	}
+	for _, target := range []string{"token", "db.local"} {
+		if _, err := o.Load(target); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Configurator.Load(): %s %v", target, err)
+		}
+	}
+}
+
+func TestFileLoader_Rescan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a.json")
+	if err := ioutil.WriteFile(a, []byte(`{"name":"a"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := NewFileLoader()
+	if err := l.AddFile(filepath.Join(dir, "*.json")); err != nil {
+		t.Fatal(err)
+	}
+	b := filepath.Join(dir, "b.json")
+	if err := ioutil.WriteFile(b, []byte(`{"name":"b"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := l.Load("b"); item != nil || err != nil {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+	l.Rescan()
+	if item, err := l.Load("b"); err != nil || item == nil || item.String() != `{"name":"b"}` {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+
+	// The deleted config files are treated as not found.
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := l.Load("a"); item != nil || err != nil {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+}
+
+func TestFileLoader_AddFileScan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(`{}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	targets := func(l FileLoader) string {
+		var r []string
+		for _, e := range l.Files() {
+			r = append(r, e.Target)
+		}
+		return strings.Join(r, ",")
+	}
+	write("a1.json")
+	write("a2.json")
+	l := NewFileLoader()
+	if err := l.AddFile(filepath.Join(dir, "a*.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the new patterns are resolved when adding, and the exclude patterns
+	// also apply to the config files of the previously added patterns.
+	write("a3.json")
+	write("b1.json")
+	if err := l.AddFile(filepath.Join(dir, "b*.json"), "!"+filepath.Join(dir, "a2.json")); err != nil {
+		t.Fatal(err)
+	}
+	if got := targets(l); got != "a1,b1" {
+		t.Fatalf("FileLoader.Files(): %s", got)
+	}
+	l.Rescan()
+	if got := targets(l); got != "a1,a3,b1" {
+		t.Fatalf("FileLoader.Files(): %s", got)
+	}
+}
+
+func TestFileLoader_SetAutoRescan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l := NewFileLoader().MustAddFile(filepath.Join(dir, "*.{json,yaml}")).SetAutoRescan(time.Nanosecond)
+	a := filepath.Join(dir, "a.json")
+	if err := ioutil.WriteFile(a, []byte(`{"name":"a"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := l.Load("a"); err != nil || item == nil || item.String() != `{"name":"a"}` {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+
+	// The config file of the same name is found after the previous one is deleted.
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte("name: a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := l.Load("a"); err != nil || item == nil || item.String() != "name: a" {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+
+	// The automatic rescanning is disabled.
+	l.SetAutoRescan(0)
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := l.Load("b"); item != nil || err != nil {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+}
+
+func TestConfigurator_SetAutoRescan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	o := New().SetCache(&CacheOptions{}).SetAutoRescan(time.Nanosecond)
+	if err := o.AddFile(filepath.Join(dir, "*.{json,yaml}")); err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(dir, "a.json")
+	if err := ioutil.WriteFile(a, []byte(`{"name":"a"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if tree, err := o.LoadTree("a"); err != nil || tree.GetString("name") != "a" {
+		t.Fatalf("Configurator.LoadTree(): %v", err)
+	}
+
+	// The cache entries of the replaced config file are invalidated by rescanning.
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte("name: b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Load("unknown"); err != ErrNotFound {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	if tree, err := o.LoadTree("a"); err != nil || tree.GetString("name") != "b" {
+		t.Fatalf("Configurator.LoadTree(): %v", err)
+	}
+
+	// The cache entries of the deleted config file are invalidated by rescanning.
+	if err := os.Remove(filepath.Join(dir, "a.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Load("unknown"); err != ErrNotFound {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	if _, err := o.Load("a"); err != ErrNotFound {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+}
+
+func TestConfigurator_Rescan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	o := New()
+	if err := o.AddFile(filepath.Join(dir, "*.json")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "a.json")
+	if err := ioutil.WriteFile(path, []byte(`{"name":"a"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Load("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	o.Rescan()
+	if item, err := o.Load("a"); err != nil || item.String() != `{"name":"a"}` {
+		t.Fatalf("Configurator.Load(): %v %v", item, err)
+	}
+
+	// The cached config items are purged by rescanning.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	o.Rescan()
+	if _, err := o.Load("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+
+	o.SetAutoRescan(time.Nanosecond)
+	if err := ioutil.WriteFile(path, []byte(`{"name":"b"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := o.Load("a"); err != nil || item.String() != `{"name":"b"}` {
+		t.Fatalf("Configurator.Load(): %v %v", item, err)
+	}
+}
+
+func TestFileLoader_RemoveFile(t *testing.T) {
+	l := NewFileLoader()
+	if err := l.AddFile("test/dirs/foo/*", "!b.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AddDir("test/dirs", "bar/*"); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Targets(); !reflect.DeepEqual(got, []string{"a", "bar/a"}) {
+		t.Fatalf("FileLoader.Targets(): %v", got)
+	}
+	foo, _ := filepath.Abs("test/dirs/foo/a.json")
+	bar, _ := filepath.Abs("test/dirs/bar/a.yaml")
+	want := []FileEntry{
+		{Target: "a", Ext: ".json", Path: foo},
+		{Target: "bar/a", Ext: ".yaml", Path: bar},
+	}
+	if got := l.Files(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("FileLoader.Files(): %v", got)
+	}
+
+	if !l.RemoveFile("!b.json") || l.RemoveFile("!b.json") {
+		t.Fatal("FileLoader.RemoveFile(): removed")
+	}
+	if got := l.Targets(); !reflect.DeepEqual(got, []string{"a", "b", "bar/a"}) {
+		t.Fatalf("FileLoader.Targets(): %v", got)
+	}
+	if !l.RemoveFile("test/dirs/bar/*") || l.RemoveFile("test/dirs/baz/*") {
+		t.Fatal("FileLoader.RemoveFile(): removed")
+	}
+	if got := l.Targets(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("FileLoader.Targets(): %v", got)
+	}
+
+	l.Reset()
+	if got := l.Targets(); len(got) != 0 || len(l.Files()) != 0 {
+		t.Fatalf("FileLoader.Targets(): %v", got)
+	}
+	if item, err := l.Load("a"); item != nil || err != nil {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+	// The removed patterns are not rescanned.
+	l.Rescan()
+	if got := l.Targets(); len(got) != 0 {
+		t.Fatalf("FileLoader.Targets(): %v", got)
+	}
+}
+
+func TestConfigurator_RemoveFile(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/dirs/foo/*"); err != nil {
+		t.Fatal(err)
+	}
+	if got := o.Targets(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("Configurator.Targets(): %v", got)
+	}
+	if got := o.Files(); len(got) != 2 || got[1].Target != "b" || !filepath.IsAbs(got[1].Path) {
+		t.Fatalf("Configurator.Files(): %v", got)
+	}
+	if _, err := o.Load("a"); err != nil {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	if o.RemoveFile("test/dirs/bar/*") || !o.RemoveFile("test/dirs/foo/*") {
+		t.Fatal("Configurator.RemoveFile(): removed")
+	}
+	// The cached config items are purged.
+	if _, err := o.Load("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+}
+
+func TestConfigurator_Reset(t *testing.T) {
+	o := New().SetCache(&CacheOptions{})
+	if err := o.AddFile("test/dirs/foo/*"); err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		Name string `json:"name"`
+	}
+	if err := o.LoadJSON("a", &v); err != nil {
+		t.Fatalf("Configurator.LoadJSON(): %v", err)
+	}
+	var events []Event
+	o.(*configurator).fs.onRescan(func(e Event) { events = append(events, e) })
+
+	o.Reset()
+	if len(o.Files()) != 0 || len(events) != 2 || events[0].Op != Remove {
+		t.Fatalf("Configurator.Reset(): %v %+v", o.Files(), events)
+	}
+	// The cached config items are purged.
+	if _, err := o.Load("a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+	if err := o.LoadJSON("a", &v); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Configurator.LoadJSON(): %v", err)
+	}
+}
+
+func TestFileLoader_SetFormatOrder(t *testing.T) {
+	l := NewFileLoader().MustAddFile("test/test.*")
+	paths := func(entries []FileEntry) []string {
+		r := make([]string, len(entries))
+		for i := range entries {
+			r[i] = filepath.Base(entries[i].Path)
+		}
+		return r
+	}
+	// The latest registered config file wins by default.
+	want := []string{"test.yaml", "test.xml", "test.txt", "test.toml", "test.json"}
+	if got := paths(l.Candidates("test")); !reflect.DeepEqual(got, want) {
+		t.Fatalf("FileLoader.Candidates(): %v", got)
+	}
+
+	l.SetFormatOrder(FormatTOML, FormatJSON)
+	want = []string{"test.toml", "test.json", "test.yaml", "test.xml", "test.txt"}
+	if got := paths(l.Candidates("test")); !reflect.DeepEqual(got, want) {
+		t.Fatalf("FileLoader.Candidates(): %v", got)
+	}
+	if item, err := l.Load("test"); err != nil || AsFormatItem(item).Format() != FormatTOML {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+	if item, err := l.Load("test.yml"); err != nil || AsFormatItem(item).Format() != FormatYAML {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+	if got := paths(l.Candidates("test.json")); !reflect.DeepEqual(got, []string{"test.json"}) {
+		t.Fatalf("FileLoader.Candidates(): %v", got)
+	}
+	if got := l.Candidates("test.ini"); len(got) != 0 {
+		t.Fatalf("FileLoader.Candidates(): %v", got)
+	}
+	if got := l.Files(); len(got) != 5 || filepath.Base(got[0].Path) != "test.toml" {
+		t.Fatalf("FileLoader.Files(): %v", got)
+	}
+
+	// The config files of the same preference are ambiguous.
+	l.SetAmbiguityError(true)
+	if item, err := l.Load("test"); err != nil || AsFormatItem(item).Format() != FormatTOML {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+	l.SetFormatOrder()
+	_, err := l.Load("test")
+	var e *AmbiguousTargetError
+	if !errors.As(err, &e) || !errors.Is(err, ErrAmbiguousTarget) || len(e.Candidates) != 5 {
+		t.Fatalf("FileLoader.Load(): %v", err)
+	}
+	if item, err := l.Load("test.json"); err != nil || AsFormatItem(item).Format() != FormatJSON {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+	// The test.yaml is the only config file of the most preferred format.
+	l.SetFormatOrder(FormatYAML, FormatXML, FormatTOML, FormatJSON)
+	if item, err := l.Load("test"); err != nil || AsFormatItem(item).Format() != FormatYAML {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+}
+
+func TestConfigurator_SetFormatOrder(t *testing.T) {
+	o := New()
+	if err := o.AddDir("test/dirs", "*/*"); err != nil {
+		t.Fatal(err)
+	}
+	if got := o.Candidates("a"); len(got) != 2 || got[0].Target != "bar/a" || got[1].Target != "foo/a" {
+		t.Fatalf("Configurator.Candidates(): %v", got)
+	}
+	if err := o.AddFile("test/test.*"); err != nil {
+		t.Fatal(err)
+	}
+	if item, err := o.Load("test"); err != nil || AsFormatItem(item).Format() != FormatYAML {
+		t.Fatalf("Configurator.Load(): %v %v", item, err)
+	}
+	// The cached config items are purged.
+	o.SetFormatOrder(FormatJSON)
+	if item, err := o.Load("test"); err != nil || AsFormatItem(item).Format() != FormatJSON {
+		t.Fatalf("Configurator.Load(): %v %v", item, err)
+	}
+	o.SetFormatOrder().SetAmbiguityError(true)
+	if _, err := o.Load("test"); !errors.Is(err, ErrAmbiguousTarget) {
+		t.Fatalf("Configurator.Load(): %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"fmt"
+)
+
+// MapStrategy defines the strategy used to merge maps.
+type MapStrategy int
+
+// These constants define the supported map merge strategies.
+const (
+	// MapMerge merges the maps recursively, this is the default strategy.
+	MapMerge MapStrategy = iota
+
+	// MapReplace replaces the lower priority map with the higher priority map.
+	MapReplace
+)
+
+// ArrayStrategy defines the strategy used to merge arrays.
+type ArrayStrategy int
+
+// These constants define the supported array merge strategies.
+const (
+	// ArrayReplace replaces the lower priority array with the higher priority array,
+	// this is the default strategy.
+	ArrayReplace ArrayStrategy = iota
+
+	// ArrayAppend appends the elements of the higher priority array to the lower
+	// priority array.
+	ArrayAppend
+
+	// ArrayMergeByKey merges the map elements that have the same value of the
+	// MergeOptions.ArrayKey, and appends the other elements.
+	ArrayMergeByKey
+)
+
+// MergeOptions defines the options used to merge config trees.
+type MergeOptions struct {
+	// Maps is the strategy used to merge maps.
+	Maps MapStrategy
+
+	// Arrays is the strategy used to merge arrays.
+	Arrays ArrayStrategy
+
+	// ArrayKey is the key used to match the array elements when Arrays is ArrayMergeByKey.
+	ArrayKey string
+
+	// NullDelete indicates whether an explicit null value deletes the key from
+	// the lower priority tree, otherwise the null value overwrites the key.
+	NullDelete bool
+}
+
+// The defaultMergeOptions variable is used when the given merge options are nil.
+var defaultMergeOptions = new(MergeOptions)
+
+// Merge merges the given source map into the given destination map and returns
+// the destination map, the source map has the higher priority.
+// The source map will not be modified, and no value of the source map will be
+// shared with the destination map. If the given options is nil, the default
+// options are used.
+func Merge(dst, src map[string]interface{}, options *MergeOptions) map[string]interface{} {
+	if options == nil {
+		options = defaultMergeOptions
+	}
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+	for k, v := range src {
+		if v == nil && options.NullDelete {
+			delete(dst, k)
+			continue
+		}
+		dst[k] = mergeValue(dst[k], v, options)
+	}
+	return dst
+}
+
+// The mergeValue function merges the given source value into the given destination
+// value and returns the result.
+func mergeValue(dst, src interface{}, options *MergeOptions) interface{} {
+	switch s := src.(type) {
+	case map[string]interface{}:
+		if d, ok := dst.(map[string]interface{}); ok && options.Maps == MapMerge {
+			return Merge(d, s, options)
+		}
+	case []interface{}:
+		d, ok := dst.([]interface{})
+		if !ok {
+			break
+		}
+		switch options.Arrays {
+		case ArrayAppend:
+			return append(d, copyValue(s).([]interface{})...)
+		case ArrayMergeByKey:
+			return mergeArrayByKey(d, s, options)
+		}
+	}
+	return copyValue(src)
+}
+
+// The mergeArrayByKey function merges the map elements that have the same value
+// of the array key, and appends the other elements.
+func mergeArrayByKey(dst, src []interface{}, options *MergeOptions) []interface{} {
+	index := make(map[string]int)
+	for i, j := 0, len(dst); i < j; i++ {
+		if m, ok := dst[i].(map[string]interface{}); ok {
+			if k, found := m[options.ArrayKey]; found {
+				index[fmt.Sprint(k)] = i
+			}
+		}
+	}
+	for i, j := 0, len(src); i < j; i++ {
+		if m, ok := src[i].(map[string]interface{}); ok {
+			if k, found := m[options.ArrayKey]; found {
+				if n, found := index[fmt.Sprint(k)]; found {
+					dst[n] = mergeValue(dst[n], m, options)
+					continue
+				}
+			}
+		}
+		dst = append(dst, copyValue(src[i]))
+	}
+	return dst
+}
+
+// The copyValue function returns a deep copy of the given config tree value.
+func copyValue(v interface{}) interface{} {
+	switch o := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(o))
+		for k, v := range o {
+			m[k] = copyValue(v)
+		}
+		return m
+	case []interface{}:
+		r := make([]interface{}, len(o))
+		for i, j := 0, len(o); i < j; i++ {
+			r[i] = copyValue(o[i])
+		}
+		return r
+	}
+	return v
+}
+
+// NewMergeLoader creates and returns a loader that merges the config targets
+// of all the given loaders.
+// The last given loader has the highest priority. The merged config item is
+// encoded as json. If no loader can load the config target, nil Item is returned.
+func NewMergeLoader(options *MergeOptions, loaders ...Loader) Loader {
+	return LoaderFunc(func(target string) (Item, error) {
+		item, err := mergeLoad(loaders, target, options)
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		return item, err
+	})
+}
+
+// The mergeLoad function loads the given config target from all the given loaders
+// and merges them in order, the last loader has the highest priority.
+// If no loader can load the config target, ErrNotFound is returned.
+func mergeLoad(loaders []Loader, target string, options *MergeOptions) (Item, error) {
+	var m map[string]interface{}
+	for i, j := 0, len(loaders); i < j; i++ {
+		item, err := loaders[i].Load(target)
+		if err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if item == nil {
+			continue
+		}
+		v, err := itemMap(item)
+		if err != nil {
+			return nil, err
+		}
+		m = Merge(m, v, options)
+	}
+	if m == nil {
+		return nil, ErrNotFound
+	}
+	return NewItemFromValue(FormatJSON, m)
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	newDst := func() map[string]interface{} {
+		return map[string]interface{}{
+			"a": map[string]interface{}{"b": 1, "c": 2},
+			"d": []interface{}{map[string]interface{}{"id": 1, "v": 1}, 2},
+			"e": 1,
+		}
+	}
+	src := map[string]interface{}{
+		"a": map[string]interface{}{"b": 3},
+		"d": []interface{}{map[string]interface{}{"id": 1, "v": 2}, 3},
+		"e": nil,
+	}
+
+	items := []struct {
+		options *MergeOptions
+		want    map[string]interface{}
+	}{
+		{nil, map[string]interface{}{
+			"a": map[string]interface{}{"b": 3, "c": 2},
+			"d": []interface{}{map[string]interface{}{"id": 1, "v": 2}, 3},
+			"e": nil,
+		}},
+		{&MergeOptions{Maps: MapReplace, NullDelete: true}, map[string]interface{}{
+			"a": map[string]interface{}{"b": 3},
+			"d": []interface{}{map[string]interface{}{"id": 1, "v": 2}, 3},
+		}},
+		{&MergeOptions{Arrays: ArrayAppend}, map[string]interface{}{
+			"a": map[string]interface{}{"b": 3, "c": 2},
+			"d": []interface{}{map[string]interface{}{"id": 1, "v": 1}, 2, map[string]interface{}{"id": 1, "v": 2}, 3},
+			"e": nil,
+		}},
+		{&MergeOptions{Arrays: ArrayMergeByKey, ArrayKey: "id"}, map[string]interface{}{
+			"a": map[string]interface{}{"b": 3, "c": 2},
+			"d": []interface{}{map[string]interface{}{"id": 1, "v": 2}, 2, 3},
+			"e": nil,
+		}},
+	}
+	for i, item := range items {
+		if got := Merge(newDst(), src, item.options); !reflect.DeepEqual(got, item.want) {
+			t.Fatalf("Merge(): %d %v", i, got)
+		}
+	}
+
+	// The source map must not be shared.
+	got := Merge(nil, src, nil)
+	got["a"].(map[string]interface{})["b"] = 4
+	if src["a"].(map[string]interface{})["b"] != 3 {
+		t.Fatalf("Merge(): %v", src)
+	}
+}
+
+func TestConfigurator_LoadMerged(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/merge/base.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	o.Use(LoaderFunc(func(target string) (Item, error) {
+		if target == "base" {
+			item, err := NewFileItem("test/merge/override.json")
+			if err != nil {
+				return nil, err
+			}
+			return item, nil
+		}
+		return nil, nil
+	}))
+
+	item, err := o.LoadMerged("base", &MergeOptions{Arrays: ArrayMergeByKey, ArrayKey: "name", NullDelete: true})
+	if err != nil {
+		t.Fatalf("Configurator.LoadMerged(): %s", err)
+	}
+	if got := AsFormatItem(item).Format(); got != FormatJSON {
+		t.Fatalf("Configurator.LoadMerged(): %s", got)
+	}
+	tree, err := AsFormatItem(item).Tree()
+	if err != nil {
+		t.Fatalf("Item.Tree(): %s", err)
+	}
+	if got := tree.GetString("name"); got != "base" {
+		t.Fatalf("Configurator.LoadMerged(): %s", got)
+	}
+	if got := tree.GetString("server.host"); got != "0.0.0.0" {
+		t.Fatalf("Configurator.LoadMerged(): %s", got)
+	}
+	if got := tree.GetInt("server.port"); got != 8080 {
+		t.Fatalf("Configurator.LoadMerged(): %d", got)
+	}
+	if tree.Has("server.tls") {
+		t.Fatal("Configurator.LoadMerged(): server.tls exists")
+	}
+	if got := tree.GetString("users[1].role"); got != "admin" {
+		t.Fatalf("Configurator.LoadMerged(): %s", got)
+	}
+	if got := tree.GetString("users[2].name"); got != "c" {
+		t.Fatalf("Configurator.LoadMerged(): %s", got)
+	}
+
+	if _, err := o.LoadMerged("unknown", nil); err != ErrNotFound {
+		t.Fatalf("Configurator.LoadMerged(): %v", err)
+	}
+	o.Use(LoaderFunc(func(string) (Item, error) { return nil, errors.New("test") }))
+	if _, err := o.LoadMerged("base", nil); err == nil {
+		t.Fatal("Configurator.LoadMerged(): no error")
+	}
+}
+
+func TestNewMergeLoader(t *testing.T) {
+	l := NewMergeLoader(nil,
+		LoaderFunc(func(string) (Item, error) { return NewItemFromString(`{"a": 1, "b": 1}`), nil }),
+		LoaderFunc(func(string) (Item, error) { return nil, ErrNotFound }),
+		LoaderFunc(func(string) (Item, error) { return NewItemFromString(`b: 2`), nil }),
+	)
+	item, err := l.Load("test")
+	if err != nil {
+		t.Fatalf("MergeLoader.Load(): %s", err)
+	}
+	if item.String() != `{"a":1,"b":2}` {
+		t.Fatalf("MergeLoader.Load(): %s", item)
+	}
+
+	item, err = NewMergeLoader(nil).Load("test")
+	if err != nil || item != nil {
+		t.Fatalf("MergeLoader.Load(): %v %v", item, err)
+	}
+}
+
+func TestNewMergeLoader_Numbers(t *testing.T) {
+	l := NewMergeLoader(nil,
+		LoaderFunc(func(string) (Item, error) {
+			return NewItemFromString(`{"id": 9007199254740993, "big": 12345678901234567890, "a": {"x": 0.1}}`), nil
+		}),
+		LoaderFunc(func(string) (Item, error) { return NewItemFromString(`{"a": {"y": -9007199254740993}}`), nil }),
+		LoaderFunc(func(string) (Item, error) { return NewItemFromString("b: 9007199254740993"), nil }),
+	)
+	item, err := l.Load("test")
+	if err != nil {
+		t.Fatalf("MergeLoader.Load(): %s", err)
+	}
+	want := `{"a":{"x":0.1,"y":-9007199254740993},"b":9007199254740993,"big":12345678901234567890,"id":9007199254740993}`
+	if item.String() != want {
+		t.Fatalf("MergeLoader.Load(): %s", item)
+	}
+
+	var v struct {
+		ID int64 `json:"id"`
+		A  struct {
+			Y int64 `json:"y"`
+		} `json:"a"`
+	}
+	o := New().Use(l)
+	if err := o.LoadJSON("test", &v); err != nil || v.ID != 9007199254740993 || v.A.Y != -9007199254740993 {
+		t.Fatalf("Configurator.LoadJSON(): %+v %v", v, err)
+	}
+	if item, err := o.LoadMerged("test", nil); err != nil || item.String() != want {
+		t.Fatalf("Configurator.LoadMerged(): %v %v", item, err)
+	}
+}
@@ -0,0 +1,548 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema interface defines the validation schema of the config trees.
+type Schema interface {
+	// Validate validates the given config tree.
+	// If the config tree is invalid, a *SchemaError is returned.
+	Validate(Tree) error
+}
+
+// Violation describes a validation failure of the config tree.
+type Violation struct {
+	// Pointer is the JSON pointer of the invalid value, such as "/db/port".
+	Pointer string
+
+	// Message describes the failure.
+	Message string
+}
+
+// String returns the string representation of the violation.
+func (v Violation) String() string {
+	if v.Pointer == "" {
+		return "/: " + v.Message
+	}
+	return v.Pointer + ": " + v.Message
+}
+
+// SchemaError reports that the config tree does not match the schema.
+type SchemaError struct {
+	// Target is the name of the config target, it is empty if the config tree is
+	// validated directly.
+	Target string
+
+	// Source is the config file path, it is empty if the config tree does not
+	// come from a config file.
+	Source string
+
+	// Violations are all validation failures.
+	Violations []Violation
+}
+
+// Error returns the error message.
+func (e *SchemaError) Error() string {
+	s := "configurator: schema validation failed"
+	if e.Target != "" {
+		s += " for " + strconv.Quote(e.Target)
+	}
+	if e.Source != "" {
+		s += " in " + e.Source
+	}
+	for _, v := range e.Violations {
+		s += "\n\t" + v.String()
+	}
+	return s
+}
+
+// NewSchema creates and returns a JSON Schema from the given config item.
+// The schema can be written in any supported format, a subset of the draft 2020-12
+// is supported: type, enum, const, required, properties, additionalProperties,
+// items, minItems, maxItems, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// minLength, maxLength, pattern, allOf, anyOf, oneOf, not, $defs and local $ref.
+// Since all values of xml documents are strings, the strings are accepted as
+// numbers and booleans when validating the config trees from xml documents.
+func NewSchema(item Item) (Schema, error) {
+	t, err := AsFormatItem(item).Tree()
+	if err != nil {
+		return nil, err
+	}
+	s := &jsonSchema{root: t.Map(), patterns: make(map[string]*regexp.Regexp)}
+	// Compile all patterns in advance to report the invalid patterns early.
+	if err := s.compilePatterns(s.root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// The jsonSchema type is a built-in implementation of the Schema interface.
+type jsonSchema struct {
+	root     map[string]interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// Validate validates the given config tree.
+func (s *jsonSchema) Validate(t Tree) error {
+	v := &schemaValidator{schema: s, lenient: t.Format() == FormatXML}
+	v.validate(s.root, t.Map(), "")
+	if len(v.violations) > 0 {
+		return &SchemaError{Source: t.Source(), Violations: v.violations}
+	}
+	return nil
+}
+
+// The compilePatterns method compiles all patterns of the given schema.
+func (s *jsonSchema) compilePatterns(v interface{}) error {
+	switch o := v.(type) {
+	case map[string]interface{}:
+		for k, v := range o {
+			if p, ok := v.(string); ok && k == "pattern" {
+				re, err := regexp.Compile(p)
+				if err != nil {
+					return err
+				}
+				s.patterns[p] = re
+				continue
+			}
+			if err := s.compilePatterns(v); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, v := range o {
+			if err := s.compilePatterns(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// The resolveRef method resolves the given local reference, such as "#/$defs/port".
+func (s *jsonSchema) resolveRef(ref string) (interface{}, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+	var v interface{} = s.root
+	for _, key := range strings.Split(strings.TrimPrefix(ref[1:], "/"), "/") {
+		if key == "" {
+			continue
+		}
+		key = strings.Replace(strings.Replace(key, "~1", "/", -1), "~0", "~", -1)
+		switch o := v.(type) {
+		case map[string]interface{}:
+			var found bool
+			if v, found = o[key]; !found {
+				return nil, false
+			}
+		case []interface{}:
+			n, err := strconv.Atoi(key)
+			if err != nil || n < 0 || n >= len(o) {
+				return nil, false
+			}
+			v = o[n]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// The schemaValidator type validates a config tree against the schema.
+type schemaValidator struct {
+	schema     *jsonSchema
+	lenient    bool
+	depth      int
+	violations []Violation
+}
+
+// The fail method records a violation.
+func (v *schemaValidator) fail(pointer, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+// The check method validates the given value silently and determines whether it is valid.
+func (v *schemaValidator) check(schema, value interface{}, pointer string) bool {
+	sub := &schemaValidator{schema: v.schema, lenient: v.lenient, depth: v.depth}
+	sub.validate(schema, value, pointer)
+	return len(sub.violations) == 0
+}
+
+// The validate method validates the given value against the given schema.
+func (v *schemaValidator) validate(schema, value interface{}, pointer string) {
+	switch o := schema.(type) {
+	case bool:
+		if !o {
+			v.fail(pointer, "value is not allowed")
+		}
+		return
+	case map[string]interface{}:
+		schema := o
+		if ref, ok := schema["$ref"].(string); ok {
+			// Prevent the infinite recursion of the recursive references.
+			if v.depth > 64 {
+				v.fail(pointer, "reference %s is too deep", ref)
+				return
+			}
+			target, found := v.schema.resolveRef(ref)
+			if !found {
+				v.fail(pointer, "unresolvable reference %s", ref)
+				return
+			}
+			v.depth++
+			v.validate(target, value, pointer)
+			v.depth--
+		}
+		v.validateGeneric(schema, value, pointer)
+		v.validateCombinators(schema, value, pointer)
+		v.validateNumber(schema, value, pointer)
+		v.validateString(schema, value, pointer)
+		v.validateArray(schema, value, pointer)
+		v.validateObject(schema, value, pointer)
+	}
+}
+
+// The validateGeneric method validates the type, enum and const keywords.
+func (v *schemaValidator) validateGeneric(schema map[string]interface{}, value interface{}, pointer string) {
+	switch o := schema["type"].(type) {
+	case string:
+		if !v.isType(value, o) {
+			v.fail(pointer, "expected %s, got %s", o, typeName(value))
+		}
+	case []interface{}:
+		matched := false
+		names := make([]string, len(o))
+		for i, t := range o {
+			names[i] = fmt.Sprint(t)
+			if s, ok := t.(string); ok && v.isType(value, s) {
+				matched = true
+			}
+		}
+		if !matched {
+			v.fail(pointer, "expected %s, got %s", strings.Join(names, " or "), typeName(value))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		matched := false
+		for _, e := range enum {
+			if v.equal(e, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(pointer, "value %v is not one of %v", value, enum)
+		}
+	}
+	if c, found := schema["const"]; found && !v.equal(c, value) {
+		v.fail(pointer, "value %v is not %v", value, c)
+	}
+}
+
+// The validateCombinators method validates the allOf, anyOf, oneOf and not keywords.
+func (v *schemaValidator) validateCombinators(schema map[string]interface{}, value interface{}, pointer string) {
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, s := range allOf {
+			v.validate(s, value, pointer)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, s := range anyOf {
+			if v.check(s, value, pointer) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(pointer, "value does not match any schema of anyOf")
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		n := 0
+		for _, s := range oneOf {
+			if v.check(s, value, pointer) {
+				n++
+			}
+		}
+		if n != 1 {
+			v.fail(pointer, "value matches %d schemas of oneOf, expected exactly 1", n)
+		}
+	}
+	if not, found := schema["not"]; found && v.check(not, value, pointer) {
+		v.fail(pointer, "value must not match the schema of not")
+	}
+}
+
+// The validateNumber method validates the number keywords.
+func (v *schemaValidator) validateNumber(schema map[string]interface{}, value interface{}, pointer string) {
+	n, ok := v.number(value)
+	if !ok {
+		return
+	}
+	if m, ok := toSchemaNumber(schema["minimum"]); ok && n < m {
+		v.fail(pointer, "value %v is less than minimum %v", value, m)
+	}
+	if m, ok := toSchemaNumber(schema["maximum"]); ok && n > m {
+		v.fail(pointer, "value %v is greater than maximum %v", value, m)
+	}
+	if m, ok := toSchemaNumber(schema["exclusiveMinimum"]); ok && n <= m {
+		v.fail(pointer, "value %v is not greater than exclusiveMinimum %v", value, m)
+	}
+	if m, ok := toSchemaNumber(schema["exclusiveMaximum"]); ok && n >= m {
+		v.fail(pointer, "value %v is not less than exclusiveMaximum %v", value, m)
+	}
+	if m, ok := toSchemaNumber(schema["multipleOf"]); ok && m > 0 {
+		if r := math.Mod(n, m); r != 0 && math.Abs(r-m) > 1e-9 {
+			v.fail(pointer, "value %v is not a multiple of %v", value, m)
+		}
+	}
+}
+
+// The validateString method validates the string keywords.
+func (v *schemaValidator) validateString(schema map[string]interface{}, value interface{}, pointer string) {
+	s, ok := value.(string)
+	if !ok {
+		return
+	}
+	n := utf8.RuneCountInString(s)
+	if m, ok := toSchemaNumber(schema["minLength"]); ok && float64(n) < m {
+		v.fail(pointer, "length %d is less than minLength %v", n, m)
+	}
+	if m, ok := toSchemaNumber(schema["maxLength"]); ok && float64(n) > m {
+		v.fail(pointer, "length %d is greater than maxLength %v", n, m)
+	}
+	if p, ok := schema["pattern"].(string); ok {
+		if re := v.schema.patterns[p]; re != nil && !re.MatchString(s) {
+			v.fail(pointer, "value %q does not match pattern %q", s, p)
+		}
+	}
+}
+
+// The validateArray method validates the array keywords.
+func (v *schemaValidator) validateArray(schema map[string]interface{}, value interface{}, pointer string) {
+	a, ok := value.([]interface{})
+	if !ok {
+		return
+	}
+	if m, ok := toSchemaNumber(schema["minItems"]); ok && float64(len(a)) < m {
+		v.fail(pointer, "array length %d is less than minItems %v", len(a), m)
+	}
+	if m, ok := toSchemaNumber(schema["maxItems"]); ok && float64(len(a)) > m {
+		v.fail(pointer, "array length %d is greater than maxItems %v", len(a), m)
+	}
+	if items, found := schema["items"]; found {
+		for i := range a {
+			v.validate(items, a[i], pointer+"/"+strconv.Itoa(i))
+		}
+	}
+}
+
+// The validateObject method validates the object keywords.
+func (v *schemaValidator) validateObject(schema map[string]interface{}, value interface{}, pointer string) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			if k, ok := r.(string); ok {
+				if _, found := m[k]; !found {
+					v.fail(pointer+"/"+escapePointer(k), "required property is missing")
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// Sort the keys so that the violations are reported in a stable order.
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := pointer + "/" + escapePointer(k)
+		if s, found := properties[k]; found {
+			v.validate(s, m[k], p)
+		} else if hasAdditional {
+			if b, ok := additional.(bool); ok && !b {
+				v.fail(p, "additional property is not allowed")
+			} else {
+				v.validate(additional, m[k], p)
+			}
+		}
+	}
+}
+
+// The isType method determines whether the given value has the given JSON type.
+func (v *schemaValidator) isType(value interface{}, name string) bool {
+	switch name {
+	case "null":
+		return value == nil
+	case "boolean":
+		if _, ok := value.(bool); ok {
+			return true
+		}
+		if s, ok := value.(string); ok && v.lenient {
+			_, err := strconv.ParseBool(s)
+			return err == nil
+		}
+		return false
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := v.number(value)
+		return ok
+	case "integer":
+		n, ok := v.number(value)
+		return ok && n == math.Trunc(n)
+	}
+	return false
+}
+
+// The number method returns the number of the given value.
+func (v *schemaValidator) number(value interface{}) (float64, bool) {
+	if s, ok := value.(string); ok {
+		if !v.lenient {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		return f, err == nil
+	}
+	return toSchemaNumber(value)
+}
+
+// The equal method determines whether the given values are equal.
+func (v *schemaValidator) equal(a, b interface{}) bool {
+	if x, ok := toSchemaNumber(a); ok {
+		y, ok := v.number(b)
+		return ok && x == y
+	}
+	if x, ok := a.(bool); ok && v.lenient {
+		if s, ok := b.(string); ok {
+			y, err := strconv.ParseBool(s)
+			return err == nil && x == y
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// The toSchemaNumber function converts the given numeric value to a float64.
+func toSchemaNumber(value interface{}) (float64, bool) {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		f, err := toFloat64(value)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// The typeName function returns the JSON type name of the given value.
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	}
+	if _, ok := toSchemaNumber(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// The escapePointer function escapes the given key as a JSON pointer token.
+func escapePointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+// SetSchema attaches the given schema to the given config target.
+// If the given schema is nil, the schema of the config target is removed.
+func (o *configurator) SetSchema(target string, schema Schema) Configurator {
+	o.mutex.Lock()
+	if schema == nil {
+		delete(o.schemas, target)
+	} else {
+		if o.schemas == nil {
+			o.schemas = make(map[string]Schema)
+		}
+		o.schemas[target] = schema
+	}
+	o.mutex.Unlock()
+	// The cached config items have not been validated against the new schema.
+	o.Purge()
+	return o
+}
+
+// The schemaOf method returns the schema of the given config target.
+// If there is no schema attached to the config target, the schema attached to the
+// name of the config target is returned, for example, the schema of "name" is used
+// for "name.json".
+func (o *configurator) schemaOf(target string) Schema {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if s := o.schemas[target]; s != nil {
+		return s
+	}
+	return o.schemas[strings.TrimSuffix(target, filepath.Ext(target))]
+}
+
+// The validate method validates the given config item against the schema of
+// the given config target.
+func (o *configurator) validate(target string, item Item) error {
+	schema := o.schemaOf(target)
+	if schema == nil {
+		return nil
+	}
+	t, err := AsFormatItem(item).Tree()
+	if err != nil {
+		return err
+	}
+	if err := schema.Validate(t); err != nil {
+		if e, ok := err.(*SchemaError); ok {
+			e.Target = target
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestSchema(t *testing.T) Schema {
+	item, err := NewFileItem("test/schema/server.schema.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := NewSchema(item)
+	if err != nil {
+		t.Fatalf("NewSchema(): %s", err)
+	}
+	return schema
+}
+
+func TestNewSchema(t *testing.T) {
+	if _, err := NewSchema(NewItemFromString(`{"pattern": "("}`)); err == nil {
+		t.Fatal("NewSchema(): no error")
+	}
+	if _, err := NewSchema(NewItemFromString("")); err != ErrEmptyItem {
+		t.Fatalf("NewSchema(): %v", err)
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := newTestSchema(t)
+
+	for _, path := range []string{"test/schema/server.json", "test/schema/server.xml"} {
+		item, err := NewFileItem(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := AsFormatItem(item).Tree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := schema.Validate(tree); err != nil {
+			t.Fatalf("Schema.Validate(): %s", err)
+		}
+	}
+
+	item, err := NewFileItem("test/schema/invalid.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := AsFormatItem(item).Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = schema.Validate(tree)
+	var e *SchemaError
+	if !errors.As(err, &e) {
+		t.Fatalf("Schema.Validate(): %v", err)
+	}
+	if e.Source != item.Path() {
+		t.Fatalf("Schema.Validate(): %s", e.Source)
+	}
+	var pointers []string
+	for _, v := range e.Violations {
+		pointers = append(pointers, v.Pointer)
+	}
+	want := []string{"/hosts", "/mode", "/name", "/port", "/timeout", "/tls", "/unknown"}
+	if !reflect.DeepEqual(pointers, want) {
+		t.Fatalf("Schema.Validate(): %v", e.Violations)
+	}
+}
+
+func TestSchema_Keywords(t *testing.T) {
+	items := []struct {
+		schema string
+		value  string
+		valid  bool
+	}{
+		{`{"type": ["string", "null"]}`, `{"v": null}`, true},
+		{`{"type": ["string", "null"]}`, `{"v": 1}`, false},
+		{`{"const": 1}`, `{"v": 1.0}`, true},
+		{`{"not": {"type": "string"}}`, `{"v": "a"}`, false},
+		{`{"allOf": [{"minimum": 1}, {"maximum": 2}]}`, `{"v": 3}`, false},
+		{`{"exclusiveMinimum": 1}`, `{"v": 1}`, false},
+		{`{"exclusiveMaximum": 1}`, `{"v": 0.5}`, true},
+		{`{"multipleOf": 0.5}`, `{"v": 1.5}`, true},
+		{`{"multipleOf": 2}`, `{"v": 3}`, false},
+		{`{"maxLength": 2}`, `{"v": "abc"}`, false},
+		{`{"maxItems": 1}`, `{"v": [1, 2]}`, false},
+		{`{"additionalProperties": {"type": "integer"}}`, `{"v": {"a": 1.5}}`, false},
+		{`{"$ref": "#/$defs/unknown"}`, `{"v": 1}`, false},
+		{`false`, `{"v": 1}`, false},
+		{`true`, `{"v": 1}`, true},
+	}
+	for _, item := range items {
+		schema, err := NewSchema(NewItemFromString(`{"properties": {"v": ` + item.schema + `}}`))
+		if err != nil {
+			t.Fatalf("NewSchema(): %s", err)
+		}
+		tree, err := AsFormatItem(NewItemFromString(item.value)).Tree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := schema.Validate(tree); (err == nil) != item.valid {
+			t.Fatalf("Schema.Validate(): %s %s %v", item.schema, item.value, err)
+		}
+	}
+
+	// The recursive references are supported.
+	schema, err := NewSchema(NewItemFromString(`{"properties": {"next": {"$ref": "#"}, "v": {"type": "integer"}}}`))
+	if err != nil {
+		t.Fatalf("NewSchema(): %s", err)
+	}
+	tree, _ := AsFormatItem(NewItemFromString(`{"next": {"next": {"v": "a"}}}`)).Tree()
+	err = schema.Validate(tree)
+	if err == nil || !strings.Contains(err.Error(), "/next/next/v: expected integer, got string") {
+		t.Fatalf("Schema.Validate(): %v", err)
+	}
+}
+
+func TestConfigurator_SetSchema(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/schema/*"); err != nil {
+		t.Fatal(err)
+	}
+	o.SetSchema("server", newTestSchema(t)).SetSchema("invalid.yaml", newTestSchema(t))
+
+	var v struct {
+		Name string `json:"name" xml:"name"`
+	}
+	if err := o.LoadJSON("server.json", &v); err != nil || v.Name != "api" {
+		t.Fatalf("Configurator.LoadJSON(): %v", err)
+	}
+	if err := o.LoadXML("server.xml", &v); err != nil || v.Name != "api" {
+		t.Fatalf("Configurator.LoadXML(): %v", err)
+	}
+
+	// The schema of the full target name does not apply to the short name.
+	if _, err := o.Load("invalid"); err != nil {
+		t.Fatalf("Configurator.Load(): %s", err)
+	}
+	var e *SchemaError
+	err := o.LoadYAML("invalid.yaml", &v)
+	if !errors.As(err, &e) || e.Target != "invalid.yaml" || len(e.Violations) != 7 {
+		t.Fatalf("Configurator.LoadYAML(): %v", err)
+	}
+	if !strings.Contains(err.Error(), "invalid.yaml") {
+		t.Fatalf("Configurator.LoadYAML(): %s", err)
+	}
+
+	o.SetSchema("invalid.yaml", nil)
+	if err := o.LoadYAML("invalid.yaml", &v); err != nil {
+		t.Fatalf("Configurator.LoadYAML(): %s", err)
+	}
+}
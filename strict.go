@@ -0,0 +1,542 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// UnknownKeysError reports that the config document contains the keys that do not
+// map to the bound object.
+type UnknownKeysError struct {
+	// Target is the name of the config target, it is empty if the config item is
+	// decoded directly.
+	Target string
+
+	// Source is the config file path, it is empty if the config item does not
+	// come from a config file.
+	Source string
+
+	// Keys are the unknown keys reported by the format-native decoders. The toml and
+	// xml keys are the full paths, such as "db.tiemout", the json and yaml keys are
+	// the key names, and the json decoder reports the first unknown key only.
+	Keys []string
+}
+
+// Error returns the error message.
+func (e *UnknownKeysError) Error() string {
+	s := "configurator: unknown keys"
+	if e.Target != "" {
+		s += " for " + strconv.Quote(e.Target)
+	}
+	if e.Source != "" {
+		s += " in " + e.Source
+	}
+	for _, key := range e.Keys {
+		s += "\n\t" + key
+	}
+	return s
+}
+
+// Strict returns a config item that fails to decode if the config document contains
+// the keys that do not map to the bound object, the unknown keys are reported by
+// a *UnknownKeysError. The strict modes of the format-native decoders are used, such
+// as json.Decoder.DisallowUnknownFields, yaml.UnmarshalStrict and the undecoded keys
+// of toml, so the duplicate yaml keys are also rejected. The xml decoder has no
+// strict mode, the xml keys are checked by the xml decoding rules. The unknown keys
+// of the custom formats are not checked.
+func Strict(item Item) Item {
+	if _, ok := item.(*strictItem); ok {
+		return item
+	}
+	return &strictItem{item}
+}
+
+// The strictItem type is the config item returned by the Strict function.
+type strictItem struct {
+	Item
+}
+
+// The check method checks the unknown keys of the config document for the given
+// object decoded as the given format.
+func (item *strictItem) check(format string, v interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil
+	}
+	// The config document is decoded into a new object, so the given object is not
+	// changed if there are unknown keys. The other decoding errors are ignored here,
+	// they are reported by the decoding of the given object.
+	o := reflect.New(t.Elem()).Interface()
+	var (
+		keys []string
+		err  error
+	)
+	switch format {
+	case FormatJSON:
+		keys = jsonUnknownKeys(item.Bytes(), o)
+	case FormatYAML:
+		keys, err = yamlUnknownKeys(item.Bytes(), o)
+	case FormatTOML:
+		keys = tomlUnknownKeys(item.Bytes(), o)
+	case FormatXML:
+		keys, err = item.xmlUnknownKeys(t)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return &UnknownKeysError{Source: item.source(), Keys: keys}
+	}
+	return nil
+}
+
+// The source method returns the config file path of the config item.
+// If the config item does not come from a config file, an empty string is returned.
+func (item *strictItem) source() string {
+	if o, ok := item.Item.(FileItem); ok {
+		return o.Path()
+	}
+	return ""
+}
+
+// The xmlUnknownKeys method returns the unknown keys of the xml document for the
+// given type.
+func (item *strictItem) xmlUnknownKeys(t reflect.Type) ([]string, error) {
+	tree, err := item.Tree()
+	if err != nil {
+		return nil, err
+	}
+	c := new(xmlChecker)
+	c.check(t, tree.Map(), tree.Path())
+	return c.keys, nil
+}
+
+// The jsonUnknownKeys function decodes the given json document into the given
+// object with json.Decoder.DisallowUnknownFields, and returns the unknown key.
+func jsonUnknownKeys(data []byte, v interface{}) []string {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		s := err.Error()
+		if strings.HasPrefix(s, jsonUnknownField) {
+			if k, err := strconv.Unquote(s[len(jsonUnknownField):]); err == nil {
+				return []string{k}
+			}
+		}
+	}
+	return nil
+}
+
+// The jsonUnknownField is the message prefix of the unknown field errors returned
+// by the json decoder.
+const jsonUnknownField = "json: unknown field "
+
+// These variables are the message patterns of the yaml.UnmarshalStrict errors.
+var (
+	yamlUnknownField = regexp.MustCompile(`^line \d+: field (.+) not found in type `)
+	yamlDuplicateKey = regexp.MustCompile(`^line \d+: (key|field) .+ already set in `)
+)
+
+// The yamlUnknownKeys function decodes the given yaml document into the given
+// object with yaml.UnmarshalStrict, and returns the unknown keys. If there are
+// duplicate keys, a *yaml.TypeError with the strict mode errors is returned.
+func yamlUnknownKeys(data []byte, v interface{}) ([]string, error) {
+	e, ok := yaml.UnmarshalStrict(data, v).(*yaml.TypeError)
+	if !ok {
+		return nil, nil
+	}
+	var keys, errs []string
+	duplicated := false
+	for _, s := range e.Errors {
+		if m := yamlUnknownField.FindStringSubmatch(s); m != nil {
+			keys = append(keys, m[1])
+			errs = append(errs, s)
+		} else if yamlDuplicateKey.MatchString(s) {
+			duplicated = true
+			errs = append(errs, s)
+		}
+	}
+	if duplicated {
+		return nil, &yaml.TypeError{Errors: errs}
+	}
+	return keys, nil
+}
+
+// The tomlUnknownKeys function decodes the given toml document into the given
+// object, and returns the undecoded keys.
+func tomlUnknownKeys(data []byte, v interface{}) []string {
+	md, err := toml.Decode(string(data), v)
+	if err != nil {
+		return nil
+	}
+	var keys []string
+	for _, key := range md.Undecoded() {
+		keys = append(keys, key.String())
+	}
+	return keys
+}
+
+// JSON binds the config content to the given object as json.
+func (item *strictItem) JSON(v interface{}) error {
+	if err := item.check(FormatJSON, v); err != nil {
+		return err
+	}
+	return item.Item.JSON(v)
+}
+
+// XML binds the config content to the given object as xml.
+func (item *strictItem) XML(v interface{}) error {
+	if err := item.check(FormatXML, v); err != nil {
+		return err
+	}
+	return item.Item.XML(v)
+}
+
+// TOML binds the config content to the given object as toml.
+func (item *strictItem) TOML(v interface{}) error {
+	if err := item.check(FormatTOML, v); err != nil {
+		return err
+	}
+	return item.Item.TOML(v)
+}
+
+// YAML binds the config content to the given object as yaml.
+func (item *strictItem) YAML(v interface{}) error {
+	if err := item.check(FormatYAML, v); err != nil {
+		return err
+	}
+	return item.Item.YAML(v)
+}
+
+// Format returns the format name of the config content.
+func (item *strictItem) Format() string {
+	return AsFormatItem(item.Item).Format()
+}
+
+// Decode binds the config content to the given object according to the format
+// of the config item.
+func (item *strictItem) Decode(v interface{}) error {
+	if err := item.check(item.Format(), v); err != nil {
+		return err
+	}
+	return decodeItem(item.Item, v)
+}
+
+// Tree parses the config content and returns the config tree.
+func (item *strictItem) Tree() (Tree, error) {
+	return AsFormatItem(item.Item).Tree()
+}
+
+// The checkKeys method checks the unknown keys of the given config tree for the
+// given object, a *UnknownKeysError is returned if there are unknown keys.
+func (d *treeDecoder) checkKeys(t Tree, v interface{}) error {
+	var keys []string
+	d.unknownKeys(reflect.TypeOf(v), t.Map(), t.Path(), &keys)
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return &UnknownKeysError{Source: t.Source(), Keys: keys}
+	}
+	return nil
+}
+
+// The xmlNameType is the reflect type of the xml.Name.
+var xmlNameType = reflect.TypeOf(xml.Name{})
+
+// The unknownKeys method collects the unknown keys of the given raw value for the
+// given type recursively.
+func (d *treeDecoder) unknownKeys(t reflect.Type, raw interface{}, path string, keys *[]string) {
+	if t == nil || raw == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if d.isLeaf(t, raw) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return
+		}
+		known := make(map[string]bool, len(m))
+		sorted := sortedKeys(m)
+		for _, f := range d.fields(t) {
+			k, found := d.match(f, m, sorted)
+			if !found {
+				continue
+			}
+			known[k] = true
+			d.unknownKeys(fieldType(t, f.index), m[k], joinKey(path, k), keys)
+		}
+		for _, k := range sorted {
+			if !known[k] {
+				*keys = append(*keys, joinKey(path, k))
+			}
+		}
+	case reflect.Map:
+		if m, ok := raw.(map[string]interface{}); ok {
+			for _, k := range sortedKeys(m) {
+				d.unknownKeys(t.Elem(), m[k], joinKey(path, k), keys)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		switch o := raw.(type) {
+		case []interface{}:
+			for i := range o {
+				d.unknownKeys(t.Elem(), o[i], joinIndex(path, i), keys)
+			}
+		case map[string]interface{}:
+			// A single element of the xml documents is not an array.
+			d.unknownKeys(t.Elem(), o, path, keys)
+		}
+	}
+}
+
+// The isLeaf method determines whether the given type is decoded as a whole,
+// such as the types supported by the decode hooks and encoding.TextUnmarshaler.
+func (d *treeDecoder) isLeaf(t reflect.Type, raw interface{}) bool {
+	if t == xmlNameType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	for _, hooks := range [][]DecodeHook{d.options.Hooks, builtinHooks} {
+		for _, hook := range hooks {
+			if _, ok, _ := hook.Convert(t, raw); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The fieldType function returns the type of the nested field of the given struct type.
+func fieldType(t reflect.Type, index []int) reflect.Type {
+	for _, x := range index {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		t = t.Field(x).Type
+	}
+	return t
+}
+
+// The xmlUnmarshalerType is the reflect type of the xml.Unmarshaler.
+var xmlUnmarshalerType = reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()
+
+// The xmlChecker type collects the unknown keys of the xml documents by the
+// element matching rules of the xml decoder.
+type xmlChecker struct {
+	keys []string
+}
+
+// The xmlField type describes a struct field matched by the xml decoder.
+type xmlField struct {
+	name string
+	typ  reflect.Type
+	// The parents are the names of the parent elements of the field,
+	// such as ["db"] for `xml:"db>host"`.
+	parents []string
+	// The all indicates whether the field accepts all unmatched elements,
+	// such as the ",any" fields.
+	all bool
+}
+
+// The check method collects the unknown keys of the given raw value for the given
+// type recursively.
+func (c *xmlChecker) check(t reflect.Type, raw interface{}, path string) {
+	if t == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	p := reflect.PtrTo(t)
+	if p.Implements(xmlUnmarshalerType) || p.Implements(textUnmarshalerType) {
+		return
+	}
+	if o, ok := raw.([]interface{}); ok {
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		// The repeated xml elements are decoded into the same field.
+		for i := range o {
+			c.check(t, o[i], joinIndex(path, i))
+		}
+		return
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		c.checkFields(xmlFields(t), m, path)
+	case reflect.Map:
+		for _, k := range sortedKeys(m) {
+			c.check(t.Elem(), m[k], joinKey(path, k))
+		}
+	case reflect.Slice, reflect.Array:
+		// A single element of the xml documents is not an array.
+		c.check(t.Elem(), m, path)
+	}
+}
+
+// The checkFields method collects the unknown keys of the given map for the given
+// struct fields.
+func (c *xmlChecker) checkFields(fields []xmlField, m map[string]interface{}, path string) {
+	var all bool
+	for _, f := range fields {
+		all = all || f.all
+	}
+	for _, k := range sortedKeys(m) {
+		p := joinKey(path, k)
+		known := false
+		if f := c.match(fields, k); f != nil {
+			c.check(f.typ, m[k], p)
+			known = true
+		}
+		// The text of the xml elements is ignored if there is no ",chardata" field.
+		if k == "#text" {
+			continue
+		}
+		var children []xmlField
+		for _, f := range fields {
+			if len(f.parents) > 0 && f.parents[0] == k {
+				f.parents = f.parents[1:]
+				children = append(children, f)
+			}
+		}
+		if len(children) > 0 {
+			c.checkChildren(children, m[k], p)
+			known = true
+		}
+		if !known && !all {
+			c.keys = append(c.keys, p)
+		}
+	}
+}
+
+// The checkChildren method collects the unknown keys of the given parent xml
+// element for the given struct fields.
+func (c *xmlChecker) checkChildren(fields []xmlField, raw interface{}, path string) {
+	switch o := raw.(type) {
+	case []interface{}:
+		for i := range o {
+			c.checkChildren(fields, o[i], joinIndex(path, i))
+		}
+	case map[string]interface{}:
+		c.checkFields(fields, o, path)
+	}
+}
+
+// The match method returns the struct field that the given key maps to, nil if
+// not found. The xml elements and attributes are matched case-sensitively.
+func (c *xmlChecker) match(fields []xmlField, k string) *xmlField {
+	for i := range fields {
+		f := &fields[i]
+		if !f.all && len(f.parents) == 0 && f.name == k {
+			return f
+		}
+	}
+	return nil
+}
+
+// The xmlFields function returns the struct fields of the given struct type by
+// the rules of the xml decoder. The embedded structs are flattened, and the names
+// of the xml elements and attributes are matched case-sensitively.
+func xmlFields(t reflect.Type) []xmlField {
+	var fields []xmlField
+	for i, n := 0, t.NumField(); i < n; i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("xml")
+		if (f.PkgPath != "" && !f.Anonymous) || tag == "-" || f.Name == "XMLName" {
+			continue
+		}
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, xmlFields(ft)...)
+				continue
+			}
+		}
+		parts := strings.Split(tag, ",")
+		name, attr := parts[0], false
+		for _, option := range parts[1:] {
+			switch option {
+			case "attr":
+				attr = true
+			case "chardata", "cdata":
+				name = "#text"
+			case "any", "innerxml":
+				fields = append(fields, xmlField{all: true})
+				name = "-"
+			case "comment":
+				name = "-"
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		// Only the local names of the xml elements and attributes are used.
+		if i := strings.LastIndexByte(name, ' '); i >= 0 {
+			name = name[i+1:]
+		}
+		var parents []string
+		if !attr && strings.Contains(name, ">") {
+			parents = strings.Split(name, ">")
+			name, parents = parents[len(parents)-1], parents[:len(parents)-1]
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, xmlField{name: name, typ: f.Type, parents: parents})
+	}
+	return fields
+}
+
+// SetStrict enables or disables the strict mode.
+func (o *configurator) SetStrict(strict bool) Configurator {
+	o.mutex.Lock()
+	o.strict = strict
+	o.mutex.Unlock()
+	// The cached config objects have not been checked in the strict mode.
+	o.Purge()
+	return o
+}
+
+// The strictEnabled method determines whether the strict mode is enabled.
+func (o *configurator) strictEnabled() bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.strict
+}
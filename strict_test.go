@@ -0,0 +1,178 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+type testStrictUser struct {
+	Name string `json:"name" yaml:"name" toml:"name" xml:"name,attr" config:"name"`
+}
+
+type testStrictServer struct {
+	XMLName xml.Name `json:"-" yaml:"-" toml:"-" xml:"server"`
+	Name    string   `json:"name" yaml:"name" toml:"name" xml:"name,attr" config:"name"`
+	DB      struct {
+		Host string `json:"host" yaml:"host" toml:"host" xml:"host,attr" config:"host"`
+	} `json:"db" yaml:"db" toml:"db" xml:"db" config:"db"`
+	Users   []testStrictUser  `json:"users" yaml:"users" toml:"users" xml:"users" config:"users"`
+	Labels  map[string]string `json:"labels" yaml:"labels" toml:"labels" xml:"-" config:"labels"`
+	Created time.Time         `json:"created" yaml:"created" toml:"created" xml:"created" config:"created"`
+}
+
+func TestStrict(t *testing.T) {
+	want := []string{"db.hsot", "tiemout", "users[1].nmae"}
+	items := map[string]func(Item, interface{}) error{
+		"json": Item.JSON,
+		"yaml": Item.YAML,
+		"toml": Item.TOML,
+		"xml":  decodeItem,
+	}
+	for ext, decode := range items {
+		item, err := NewFileItem("test/strict/server." + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v testStrictServer
+		if err := decode(item, &v); err != nil {
+			t.Fatalf("Item.Decode(): %s %s", ext, err)
+		}
+		err = decode(Strict(Strict(item)), &v)
+		var e *UnknownKeysError
+		if !errors.As(err, &e) || e.Source != item.Path() {
+			t.Fatalf("Strict(): %s %v", ext, err)
+		}
+		// The keys are reported by the format-native decoders.
+		keys := map[string][]string{
+			"json": {"tiemout"},
+			"yaml": {"hsot", "nmae", "tiemout"},
+			"toml": {"db.hsot", "tiemout", "users.nmae"},
+			// The xml field of the map is ignored.
+			"xml": {"db.hsot", "labels", "tiemout", "users[1].nmae"},
+		}[ext]
+		if !reflect.DeepEqual(e.Keys, keys) {
+			t.Fatalf("Strict(): %s %v", ext, e.Keys)
+		}
+
+		// The format independent decoder.
+		err = DecodeItem(item, &v, &DecodeOptions{Strict: true})
+		if !errors.As(err, &e) || !reflect.DeepEqual(e.Keys, want) {
+			t.Fatalf("DecodeItem(): %s %v", ext, err)
+		}
+	}
+
+	var m map[string]interface{}
+	if err := Strict(NewItemFromString(`{"a": {"b": 1}}`)).JSON(&m); err != nil {
+		t.Fatalf("Strict(): %s", err)
+	}
+	var v struct {
+		A map[string]struct {
+			B int `json:"b"`
+		} `json:"A"`
+		C []*struct{ D int }
+	}
+	err := Strict(NewItemFromString(`{"a": {"x": {"b": 1}}, "c": [{"d": 1, "e": 2}]}`)).JSON(&v)
+	if err == nil || !strings.HasSuffix(err.Error(), "\n\te") {
+		t.Fatalf("Strict(): %v", err)
+	}
+}
+
+func TestStrictNativeRules(t *testing.T) {
+	type server struct {
+		MaxConns int
+		Host     string `xml:"db>host"`
+		Port     int    `xml:"db>port,omitempty"`
+	}
+	var e *UnknownKeysError
+
+	// The yaml decoder matches the untagged fields by the lower case field names.
+	var v server
+	err := Strict(NewItemFromString("MaxConns: 5\nmaxconns: 6\n")).YAML(&v)
+	if !errors.As(err, &e) || !reflect.DeepEqual(e.Keys, []string{"MaxConns"}) || v.MaxConns != 0 {
+		t.Fatalf("Strict(): yaml %v", err)
+	}
+	// The yaml decoder rejects the duplicate keys in the strict mode.
+	var ye *yaml.TypeError
+	err = Strict(NewItemFromString("host: a\nhost: b\n")).YAML(&v)
+	if !errors.As(err, &ye) || len(ye.Errors) != 1 || !strings.Contains(ye.Errors[0], "already set") {
+		t.Fatalf("Strict(): yaml %v", err)
+	}
+	// The json and toml decoders match the keys case-insensitively.
+	if err := Strict(NewItemFromString(`{"MAXCONNS": 5}`)).JSON(&v); err != nil || v.MaxConns != 5 {
+		t.Fatalf("Strict(): json %v", err)
+	}
+	if err := Strict(NewItemFromString("maxconns = 6")).TOML(&v); err != nil || v.MaxConns != 6 {
+		t.Fatalf("Strict(): toml %v", err)
+	}
+
+	// The xml decoder matches the nested elements by the parent elements.
+	content := `<server><MaxConns>7</MaxConns><db><host>db1</host><port>3306</port></db></server>`
+	if err := Strict(NewItemFromString(content)).XML(&v); err != nil || v.Host != "db1" || v.Port != 3306 {
+		t.Fatalf("Strict(): xml %v", err)
+	}
+	content = `<server><maxconns>7</maxconns><db><host>db1</host><hsot>db2</hsot></db><db><prot/></db></server>`
+	err = Strict(NewItemFromString(content)).XML(&v)
+	if !errors.As(err, &e) || !reflect.DeepEqual(e.Keys, []string{"db[0].hsot", "db[1].prot", "maxconns"}) {
+		t.Fatalf("Strict(): xml %v", err)
+	}
+}
+
+func TestConfigurator_SetStrict(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/strict/*"); err != nil {
+		t.Fatal(err)
+	}
+	var v testStrictServer
+	if err := o.LoadYAML("server", &v); err != nil {
+		t.Fatalf("Configurator.LoadYAML(): %s", err)
+	}
+	o.SetStrict(true)
+	for _, cache := range []bool{false, true} {
+		if cache {
+			o.SetCache(&CacheOptions{})
+		}
+		var e *UnknownKeysError
+		if err := o.LoadYAML("server", &v); !errors.As(err, &e) || e.Target != "server" || len(e.Keys) != 3 {
+			t.Fatalf("Configurator.LoadYAML(): %v", err)
+		}
+		if err := o.LoadInto("server.json", &v); !errors.As(err, &e) || e.Target != "server.json" || len(e.Keys) != 1 {
+			t.Fatalf("Configurator.LoadInto(): %v", err)
+		}
+		if !strings.Contains(e.Error(), `unknown keys for "server.json" in `) {
+			t.Fatalf("Configurator.LoadInto(): %s", e)
+		}
+		if err := o.LoadConfig("server.json", &v); !errors.As(err, &e) || len(e.Keys) != 3 {
+			t.Fatalf("Configurator.LoadConfig(): %v", err)
+		}
+		if err := o.LoadYAML("valid", &v); err != nil {
+			t.Fatalf("Configurator.LoadYAML(): %s", err)
+		}
+		if err := o.LoadInto("valid", &v); err != nil {
+			t.Fatalf("Configurator.LoadInto(): %s", err)
+		}
+	}
+	o.SetStrict(false)
+	if err := o.LoadInto("server.json", &v); err != nil {
+		t.Fatalf("Configurator.LoadInto(): %s", err)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tree interface defines the parsed config content tree.
+// The path parameter of the methods is a dotted path, such as "db.primary.hosts[0]",
+// the array elements can be accessed by "[index]" or ".index". An empty path
+// represents the current tree itself.
+type Tree interface {
+	Getter
+
+	// Get returns the value of the given path.
+	// If the given path does not exist, nil and false are returned.
+	Get(string) (interface{}, bool)
+
+	// Has determines whether the given path exists.
+	Has(string) bool
+
+	// Keys returns the sorted keys of the current tree.
+	Keys() []string
+
+	// Sub returns a scoped view of the given path.
+	// If the given path does not exist or is not a map, an empty tree is returned.
+	Sub(string) Tree
+
+	// Map returns the map of the current tree.
+	Map() map[string]interface{}
+
+	// Path returns the full path of the current tree, it is empty for the root tree.
+	Path() string
+
+	// Source returns the config file path of the current tree.
+	// If the current tree does not come from a config file, an empty string is returned.
+	Source() string
+
+	// Format returns the format name of the config item the current tree is parsed from.
+	// If the current tree is not parsed from a config item, an empty string is returned.
+	Format() string
+}
+
+// NewTree creates and returns a config tree from the given map.
+// The nested maps and arrays of the given map will be normalized, for example,
+// map[interface{}]interface{} will be converted to map[string]interface{}.
+func NewTree(m map[string]interface{}) Tree {
+	return newTree(normalizeMap(m), "", "", "")
+}
+
+// The newTree function creates and returns a new tree instance.
+func newTree(m map[string]interface{}, path, source, format string) *tree {
+	if m == nil {
+		m = make(map[string]interface{})
+	}
+	return &tree{data: m, path: path, source: source, format: format}
+}
+
+// The tree type is a built-in implementation of the Tree interface.
+type tree struct {
+	data   map[string]interface{}
+	path   string
+	source string
+	format string
+}
+
+// Get returns the value of the given path.
+// If the given path does not exist, nil and false are returned.
+func (t *tree) Get(path string) (interface{}, bool) {
+	keys, err := splitPath(path)
+	if err != nil {
+		return nil, false
+	}
+	var v interface{} = t.data
+	for i, j := 0, len(keys); i < j; i++ {
+		switch o := v.(type) {
+		case map[string]interface{}:
+			if v = o[keys[i]]; v == nil {
+				if _, found := o[keys[i]]; !found {
+					return nil, false
+				}
+			}
+		case []interface{}:
+			n, err := strconv.Atoi(keys[i])
+			if err != nil || n < 0 || n >= len(o) {
+				return nil, false
+			}
+			v = o[n]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// Has determines whether the given path exists.
+func (t *tree) Has(path string) bool {
+	_, found := t.Get(path)
+	return found
+}
+
+// Keys returns the sorted keys of the current tree.
+func (t *tree) Keys() []string {
+	keys := make([]string, 0, len(t.data))
+	for k := range t.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Sub returns a scoped view of the given path.
+// If the given path does not exist or is not a map, an empty tree is returned.
+func (t *tree) Sub(path string) Tree {
+	m, _ := t.Get(path)
+	o, _ := m.(map[string]interface{})
+	return newTree(o, t.join(path), t.source, t.format)
+}
+
+// Map returns the map of the current tree.
+func (t *tree) Map() map[string]interface{} {
+	return t.data
+}
+
+// Path returns the full path of the current tree, it is empty for the root tree.
+func (t *tree) Path() string {
+	return t.path
+}
+
+// Source returns the config file path of the current tree.
+// If the current tree does not come from a config file, an empty string is returned.
+func (t *tree) Source() string {
+	return t.source
+}
+
+// Format returns the format name of the config item the current tree is parsed from.
+// If the current tree is not parsed from a config item, an empty string is returned.
+func (t *tree) Format() string {
+	return t.format
+}
+
+// The join method returns the full path of the given relative path.
+func (t *tree) join(path string) string {
+	switch {
+	case t.path == "":
+		return path
+	case path == "":
+		return t.path
+	case path[0] == '[':
+		return t.path + path
+	}
+	return t.path + "." + path
+}
+
+// The splitPath function splits the given dotted path into keys.
+// For example: Given "db.hosts[0].name", returns ["db", "hosts", "0", "name"].
+func splitPath(path string) ([]string, error) {
+	var keys []string
+	for _, s := range strings.Split(path, ".") {
+		for s != "" {
+			i := strings.IndexByte(s, '[')
+			if i == -1 {
+				keys = append(keys, s)
+				break
+			}
+			if i > 0 {
+				keys = append(keys, s[:i])
+			}
+			j := strings.IndexByte(s, ']')
+			if j < i {
+				return nil, fmt.Errorf("configurator: invalid path %q", path)
+			}
+			keys = append(keys, s[i+1:j])
+			s = s[j+1:]
+		}
+	}
+	return keys, nil
+}
+
+// The normalizeMap function normalizes all nested values of the given map.
+func normalizeMap(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		m[k] = normalizeValue(v)
+	}
+	return m
+}
+
+// The normalizeValue function converts the decoded values of the different
+// formats into the unified types, all maps will be converted to
+// map[string]interface{} and all arrays will be converted to []interface{}.
+func normalizeValue(v interface{}) interface{} {
+	switch o := v.(type) {
+	case map[string]interface{}:
+		return normalizeMap(o)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(o))
+		for k, v := range o {
+			m[fmt.Sprint(k)] = normalizeValue(v)
+		}
+		return m
+	case []interface{}:
+		for i, j := 0, len(o); i < j; i++ {
+			o[i] = normalizeValue(o[i])
+		}
+		return o
+	case []map[string]interface{}:
+		r := make([]interface{}, len(o))
+		for i, j := 0, len(o); i < j; i++ {
+			r[i] = normalizeMap(o[i])
+		}
+		return r
+	}
+	return v
+}
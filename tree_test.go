@@ -0,0 +1,130 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewTree(t *testing.T) {
+	o := NewTree(map[string]interface{}{
+		"a": map[interface{}]interface{}{1: "b"},
+		"c": []map[string]interface{}{{"d": "e"}},
+	})
+	if o == nil {
+		t.Fatal("NewTree(): nil")
+	}
+	if v, found := o.Get("a.1"); !found || v != "b" {
+		t.Fatalf("NewTree(): %v", v)
+	}
+	if v, found := o.Get("c[0].d"); !found || v != "e" {
+		t.Fatalf("NewTree(): %v", v)
+	}
+}
+
+func TestItem_Tree(t *testing.T) {
+	for _, path := range []string{"test/tree.json", "test/tree.yaml", "test/tree.toml", "test/tree.xml"} {
+		item, err := NewFileItem(path)
+		if err != nil {
+			t.Fatalf("NewFileItem(): %s", err)
+		}
+		o, err := AsFormatItem(item).Tree()
+		if err != nil {
+			t.Fatalf("Item.Tree(): %s", err)
+		}
+
+		if v, found := o.Get("name"); !found || v != "tree" {
+			t.Fatalf("Tree.Get(): %s %v", path, v)
+		}
+		if v, found := o.Get("db.primary.hosts[1]"); !found || v != "10.0.0.2" {
+			t.Fatalf("Tree.Get(): %s %v", path, v)
+		}
+		if v, found := o.Get("db.primary.hosts.0"); !found || v != "10.0.0.1" {
+			t.Fatalf("Tree.Get(): %s %v", path, v)
+		}
+		if v, found := o.Get("db.primary.port"); !found || fmt.Sprint(v) != "3306" {
+			t.Fatalf("Tree.Get(): %s %v", path, v)
+		}
+		for _, s := range []string{"unknown", "db.unknown", "db.primary.hosts[2]", "name.sub", "db[0"} {
+			if v, found := o.Get(s); found || v != nil {
+				t.Fatalf("Tree.Get(): %s %v", path, v)
+			}
+		}
+
+		if !o.Has("db.primary") || o.Has("db.secondary") {
+			t.Fatalf("Tree.Has(): %s", path)
+		}
+		if got := o.Keys(); !reflect.DeepEqual(got, []string{"db", "name"}) {
+			t.Fatalf("Tree.Keys(): %s %v", path, got)
+		}
+
+		abs, _ := filepath.Abs(path)
+		if got := o.Source(); got != abs {
+			t.Fatalf("Tree.Source(): %s", got)
+		}
+
+		sub := o.Sub("db.primary")
+		if got := sub.Path(); got != "db.primary" {
+			t.Fatalf("Tree.Sub(): %s", got)
+		}
+		if got := sub.Keys(); !reflect.DeepEqual(got, []string{"hosts", "port"}) {
+			t.Fatalf("Tree.Sub(): %s %v", path, got)
+		}
+		if v, found := sub.Get("hosts[0]"); !found || v != "10.0.0.1" {
+			t.Fatalf("Tree.Sub(): %s %v", path, v)
+		}
+		if got := sub.Source(); got != abs {
+			t.Fatalf("Tree.Sub(): %s", got)
+		}
+
+		empty := o.Sub("unknown")
+		if got := len(empty.Keys()); got != 0 {
+			t.Fatalf("Tree.Sub(): %d", got)
+		}
+	}
+}
+
+func TestItem_TreeError(t *testing.T) {
+	if o, err := AsFormatItem(NewItemFromString("")).Tree(); err != ErrEmptyItem || o != nil {
+		t.Fatalf("Item.Tree(): %v %v", o, err)
+	}
+	if o, err := AsFormatItem(NewItemFromString("test")).Tree(); err == nil || o != nil {
+		t.Fatalf("Item.Tree(): %v %v", o, err)
+	}
+}
+
+func TestConfigurator_LoadTree(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/tree.*"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{"tree.json", "tree.yaml", "tree.toml", "tree.xml"} {
+		tree, err := o.LoadTree(target)
+		if err != nil {
+			t.Fatalf("Configurator.LoadTree(): %s", err)
+		}
+		if v, _ := tree.Get("name"); v != "tree" {
+			t.Fatalf("Configurator.LoadTree(): %v", v)
+		}
+	}
+
+	if _, err := o.LoadTree("unknown"); err != ErrNotFound {
+		t.Fatalf("Configurator.LoadTree(): %v", err)
+	}
+}
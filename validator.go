@@ -0,0 +1,373 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidateTag is the struct tag name used by the built-in validator.
+const ValidateTag = "validate"
+
+// Validator interface defines the validator of the bound config objects.
+type Validator interface {
+	// Validate validates the given bound config object.
+	Validate(interface{}) error
+}
+
+// ValidatorFunc type defines the validator function.
+type ValidatorFunc func(interface{}) error
+
+// Validate validates the given bound config object.
+func (f ValidatorFunc) Validate(v interface{}) error {
+	return f(v)
+}
+
+// FieldError describes a validation failure of the struct field.
+type FieldError struct {
+	// Path is the path of the invalid field, such as "DB.Hosts[0].Port".
+	Path string
+
+	// Rule is the failed validation rule, such as "required" or "max".
+	Rule string
+
+	// Param is the parameter of the failed validation rule, it is empty if the
+	// rule has no parameter.
+	Param string
+
+	// Message describes the failure.
+	Message string
+}
+
+// String returns the string representation of the field error.
+func (e FieldError) String() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError reports that the bound config object is invalid.
+type ValidationError struct {
+	// Target is the name of the config target, it is empty if the config object is
+	// validated directly.
+	Target string
+
+	// Fields are all validation failures.
+	Fields []FieldError
+}
+
+// Error returns the error message.
+func (e *ValidationError) Error() string {
+	s := "configurator: validation failed"
+	if e.Target != "" {
+		s += " for " + strconv.Quote(e.Target)
+	}
+	for _, f := range e.Fields {
+		s += "\n\t" + f.String()
+	}
+	return s
+}
+
+// NewValidator creates and returns a Validator driven by the "validate" struct tags,
+// such as `validate:"required,min=1,max=65535"`. The supported rules are:
+//
+//	required   The value must not be the zero value, the length of the strings,
+//	           slices and maps must not be zero.
+//	omitempty  The other rules are skipped if the value is the zero value.
+//	min=N      The minimum number, or the minimum length of the strings, slices
+//	           and maps. The durations can be given as "1s".
+//	max=N      The maximum number, or the maximum length.
+//	len=N      The exact length of the strings, slices and maps.
+//	oneof=A B  The value must be one of the given space separated values.
+//
+// The nested structs and the elements of the slices, arrays and maps are validated
+// recursively. All failures are collected and returned by a *ValidationError.
+func NewValidator() Validator {
+	return tagValidator{}
+}
+
+// The tagValidator type is the built-in implementation of the Validator interface.
+type tagValidator struct{}
+
+// Validate validates the given bound config object.
+func (tagValidator) Validate(v interface{}) error {
+	w := new(fieldWalker)
+	if err := w.walk(reflect.ValueOf(v), ""); err != nil {
+		return err
+	}
+	if len(w.errors) > 0 {
+		return &ValidationError{Fields: w.errors}
+	}
+	return nil
+}
+
+// The fieldWalker type collects the validation failures of the struct fields.
+type fieldWalker struct {
+	errors []FieldError
+}
+
+// The walk method validates the struct fields of the given value recursively.
+// The returned error reports an invalid validation rule.
+func (w *fieldWalker) walk(v reflect.Value, path string) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i, n := 0, t.NumField(); i < n; i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && !f.Anonymous {
+				// Ignore the unexported fields.
+				continue
+			}
+			tag := f.Tag.Get(ValidateTag)
+			if tag == "-" {
+				continue
+			}
+			p := f.Name
+			if f.Anonymous {
+				p = path
+			} else if path != "" {
+				p = path + "." + f.Name
+			}
+			if tag != "" {
+				ok, err := w.check(v.Field(i), p, tag)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+			if err := w.walk(v.Field(i), p); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i, n := 0, v.Len(); i < n; i++ {
+			if err := w.walk(v.Index(i), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Sort the keys so that the failures are reported in a stable order.
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = fmt.Sprint(key.Interface())
+		}
+		sort.Sort(keySorter{keys, names})
+		for i, key := range keys {
+			if err := w.walk(v.MapIndex(key), path+"["+names[i]+"]"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// The keySorter type sorts the map keys by their string representations.
+type keySorter struct {
+	keys  []reflect.Value
+	names []string
+}
+
+func (s keySorter) Len() int           { return len(s.keys) }
+func (s keySorter) Less(i, j int) bool { return s.names[i] < s.names[j] }
+func (s keySorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+}
+
+// The check method checks the given field value against the given validation rules.
+// It returns false if the field value is invalid or skipped by the "omitempty" rule,
+// then the field value is not validated recursively.
+func (w *fieldWalker) check(v reflect.Value, path, tag string) (bool, error) {
+	rules := strings.Split(tag, ",")
+	for _, rule := range rules {
+		if strings.TrimSpace(rule) == "omitempty" && isEmptyValue(v) {
+			return false, nil
+		}
+	}
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" || rule == "omitempty" {
+			continue
+		}
+		name, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		msg, err := checkRule(v, name, param)
+		if err != nil {
+			return false, fmt.Errorf("configurator: invalid validation rule %q of %s: %s", rule, path, err)
+		}
+		if msg != "" {
+			w.errors = append(w.errors, FieldError{Path: path, Rule: name, Param: param, Message: msg})
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// The checkRule function checks the given value against the given rule.
+// It returns the failure message if the value is invalid.
+func checkRule(v reflect.Value, name, param string) (string, error) {
+	if name == "required" {
+		if isEmptyValue(v) {
+			return "is required", nil
+		}
+		return "", nil
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			// The nil values are checked by the "required" rule only.
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	switch name {
+	case "min", "max", "len":
+		if param == "" {
+			return "", fmt.Errorf("missing parameter")
+		}
+		return checkBound(v, name, param)
+	case "oneof":
+		values := strings.Fields(param)
+		if len(values) == 0 {
+			return "", fmt.Errorf("missing parameter")
+		}
+		var s string
+		switch v.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			s = fmt.Sprint(v.Interface())
+		default:
+			return "", fmt.Errorf("unsupported type %s", v.Type())
+		}
+		for _, value := range values {
+			if s == value {
+				return "", nil
+			}
+		}
+		return "must be one of [" + strings.Join(values, " ") + "]", nil
+	}
+	return "", fmt.Errorf("unknown rule")
+}
+
+// The checkBound function checks the given value against the "min", "max" and
+// "len" rules.
+func checkBound(v reflect.Value, name, param string) (string, error) {
+	var (
+		n      float64
+		length bool
+		bound  float64
+	)
+	switch v.Kind() {
+	case reflect.String:
+		n, length = float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, length = float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		return "", fmt.Errorf("unsupported type %s", v.Type())
+	}
+	if name == "len" && !length {
+		return "", fmt.Errorf("unsupported type %s", v.Type())
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			return "", err
+		}
+		bound = float64(d)
+	} else {
+		f, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return "", err
+		}
+		bound = f
+	}
+
+	var msg string
+	switch {
+	case name == "min" && n < bound:
+		msg = "must be at least " + param
+	case name == "max" && n > bound:
+		msg = "must be at most " + param
+	case name == "len" && n != bound:
+		msg = "must be exactly " + param
+	default:
+		return "", nil
+	}
+	if length {
+		return "length " + msg, nil
+	}
+	return msg, nil
+}
+
+// The isEmptyValue function determines whether the given value is empty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// SetValidator sets the validator of the bound config objects.
+// If the given validator is nil, the bound config objects are not validated.
+func (o *configurator) SetValidator(validator Validator) Configurator {
+	o.mutex.Lock()
+	o.validator = validator
+	o.mutex.Unlock()
+	// The cached config objects have not been validated by the new validator.
+	o.Purge()
+	return o
+}
+
+// The check method validates the given bound config object of the given config target.
+func (o *configurator) check(target string, v interface{}) error {
+	o.mutex.Lock()
+	validator := o.validator
+	o.mutex.Unlock()
+	if validator == nil {
+		return nil
+	}
+	if err := validator.Validate(v); err != nil {
+		if e, ok := err.(*ValidationError); ok {
+			e.Target = target
+		}
+		return err
+	}
+	return nil
+}
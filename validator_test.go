@@ -0,0 +1,162 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testValidateHost struct {
+	Host string `yaml:"host" validate:"required"`
+	Port int    `yaml:"port" validate:"min=1,max=65535"`
+}
+
+type testValidateServer struct {
+	Name    string        `yaml:"name" validate:"required,len=3"`
+	Port    int           `yaml:"port" validate:"required,min=1,max=65535"`
+	Mode    string        `yaml:"mode" validate:"oneof=debug info"`
+	Timeout time.Duration `yaml:"timeout" validate:"min=1s"`
+	Token   *string       `yaml:"token" validate:"omitempty,min=8"`
+	DB      struct {
+		Hosts []testValidateHost `yaml:"hosts" validate:"min=1"`
+	} `yaml:"db"`
+	Labels map[string]string              `yaml:"labels"`
+	Groups map[string][]*testValidateHost `yaml:"groups"`
+}
+
+func TestNewValidator(t *testing.T) {
+	var v testValidateServer
+	item, err := NewFileItem("test/validate/server.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := item.YAML(&v); err != nil {
+		t.Fatal(err)
+	}
+	v.Groups = map[string][]*testValidateHost{"b": {nil, {Port: 1}}, "a": {{Host: "x"}}}
+
+	err = NewValidator().Validate(&v)
+	var e *ValidationError
+	if !errors.As(err, &e) {
+		t.Fatalf("Validator.Validate(): %v", err)
+	}
+	var got []string
+	for _, f := range e.Fields {
+		got = append(got, f.Path+" "+f.Rule)
+	}
+	want := []string{
+		"Port max",
+		"Mode oneof",
+		"Timeout min",
+		"DB.Hosts[1].Host required",
+		"Groups[a][0].Port min",
+		"Groups[b][1].Host required",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Validator.Validate(): %v", got)
+	}
+	if !strings.Contains(err.Error(), "Mode: must be one of [debug info]") {
+		t.Fatalf("Validator.Validate(): %s", err)
+	}
+
+	token := "secret"
+	v = testValidateServer{Name: "api", Port: 80, Mode: "info", Timeout: time.Second, Token: &token}
+	v.DB.Hosts = []testValidateHost{{Host: "db", Port: 3306}}
+	if err := NewValidator().Validate(v); err == nil || !strings.Contains(err.Error(), "Token: length must be at least 8") {
+		t.Fatalf("Validator.Validate(): %v", err)
+	}
+	token = "long-secret"
+	if err := NewValidator().Validate(v); err != nil {
+		t.Fatalf("Validator.Validate(): %s", err)
+	}
+	v.Token = nil
+	if err := NewValidator().Validate(&v); err != nil {
+		t.Fatalf("Validator.Validate(): %s", err)
+	}
+}
+
+func TestNewValidator_InvalidRule(t *testing.T) {
+	items := []interface{}{
+		&struct {
+			V int `validate:"unknown"`
+		}{},
+		&struct {
+			V int `validate:"min"`
+		}{},
+		&struct {
+			V int `validate:"max=a"`
+		}{},
+		&struct {
+			V int `validate:"len=1"`
+		}{},
+		&struct {
+			V []int `validate:"oneof=1 2"`
+		}{},
+	}
+	for _, item := range items {
+		err := NewValidator().Validate(item)
+		if err == nil || !strings.Contains(err.Error(), "invalid validation rule") {
+			t.Fatalf("Validator.Validate(): %v", err)
+		}
+	}
+}
+
+func TestConfigurator_SetValidator(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/validate/*"); err != nil {
+		t.Fatal(err)
+	}
+	var v testValidateServer
+	if err := o.LoadYAML("server", &v); err != nil {
+		t.Fatalf("Configurator.LoadYAML(): %s", err)
+	}
+
+	o.SetValidator(NewValidator())
+	var e *ValidationError
+	for _, cache := range []bool{false, true} {
+		if cache {
+			o.SetCache(&CacheOptions{})
+		}
+		err := o.LoadYAML("server", &v)
+		if !errors.As(err, &e) || e.Target != "server" || len(e.Fields) != 4 {
+			t.Fatalf("Configurator.LoadYAML(): %v", err)
+		}
+		if err := o.LoadInto("server", &v); !errors.As(err, &e) {
+			t.Fatalf("Configurator.LoadInto(): %v", err)
+		}
+	}
+
+	var called int
+	o.SetValidator(ValidatorFunc(func(interface{}) error {
+		called++
+		return nil
+	}))
+	if err := o.LoadYAML("server", &v); err != nil || called != 1 {
+		t.Fatalf("Configurator.LoadYAML(): %v %d", err, called)
+	}
+	// The validated config object is cached.
+	if err := o.LoadYAML("server", &v); err != nil || called != 1 {
+		t.Fatalf("Configurator.LoadYAML(): %v %d", err, called)
+	}
+
+	o.SetValidator(nil)
+	if err := o.LoadYAML("server", &v); err != nil || called != 1 {
+		t.Fatalf("Configurator.LoadYAML(): %v %d", err, called)
+	}
+}
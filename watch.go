@@ -0,0 +1,316 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is the default polling interval of the file watchers.
+const DefaultWatchInterval = time.Second
+
+// The watchSettle variable is the settle window of the file watchers. The changes
+// are reported after the config files have not changed for the settle window, so
+// the changes of a single write (such as truncating and writing) are merged. The
+// watchSettleMax variable limits the total waiting time of the continuous changes.
+var (
+	watchSettle    = 20 * time.Millisecond
+	watchSettleMax = time.Second
+)
+
+// Op describes a set of config file operations.
+type Op uint32
+
+// These constants define the config file operations.
+const (
+	// Create indicates that a config file was created or newly matches a pattern.
+	Create Op = 1 << iota
+
+	// Write indicates that the content of a config file was modified.
+	Write
+
+	// Remove indicates that a config file was deleted.
+	Remove
+
+	// Rename indicates that a config file was replaced by another file (for example,
+	// atomically renamed over) or moved to another path.
+	Rename
+)
+
+// String returns the string representation of the operations.
+func (op Op) String() string {
+	var names []string
+	if op&Create != 0 {
+		names = append(names, "CREATE")
+	}
+	if op&Write != 0 {
+		names = append(names, "WRITE")
+	}
+	if op&Remove != 0 {
+		names = append(names, "REMOVE")
+	}
+	if op&Rename != 0 {
+		names = append(names, "RENAME")
+	}
+	return strings.Join(names, "|")
+}
+
+// Event describes a config file change.
+type Event struct {
+	// Target is the config target name of the changed config file.
+	Target string
+
+	// Path is the absolute path of the changed config file.
+	Path string
+
+	// Op is the operations of the change.
+	Op Op
+}
+
+// WatchFunc type defines the function that handles the config file changes.
+// The function is called serially in the watching goroutine.
+type WatchFunc func(Event)
+
+// Watcher interface defines the config file watcher.
+type Watcher interface {
+	// Close stops watching the config files.
+	Close() error
+}
+
+// The fileState type records the state of a registered config file.
+type fileState struct {
+	target string
+	info   os.FileInfo
+}
+
+// Watch watches the config files and the added patterns of the current loader.
+// The given function is called for every detected change. The changes are detected
+// by polling with the given interval, and on Linux, inotify is also used to detect
+// the changes immediately. If the given interval is not positive, DefaultWatchInterval
+// is used. The registered config files are updated before the function is called,
+// so the newly created config files can be loaded in the function. The changes of the
+// same config file within a short settle window are merged into one event, for
+// example, a newly created config file that is written immediately is reported as
+// Create only.
+func (o *fileLoader) Watch(interval time.Duration, fn WatchFunc) (Watcher, error) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &fileWatcher{loader: o, fn: fn, interval: interval, done: make(chan struct{})}
+	w.states = o.rescan()
+	// If the trigger is not available, we will fall back to polling.
+	if t, err := newWatchTrigger(); err == nil {
+		w.trigger = t
+		w.watchDirs()
+	}
+	go w.run()
+	return w, nil
+}
+
+// The newWatchTrigger variable is used to create the trigger of the file watchers,
+// it can be replaced in tests.
+var newWatchTrigger = newTrigger
+
+// The trigger interface defines the notification source of the file watchers.
+type trigger interface {
+	// C returns the channel that receives a value when the watched directories change.
+	C() <-chan struct{}
+
+	// Add adds the given directory to the watch list.
+	Add(string) error
+
+	// Close closes the trigger.
+	Close() error
+}
+
+// The fileWatcher type is a built-in implementation of the Watcher interface.
+type fileWatcher struct {
+	loader   *fileLoader
+	fn       WatchFunc
+	interval time.Duration
+	states   map[string]fileState
+	trigger  trigger
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Close stops watching the config files.
+func (w *fileWatcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		if w.trigger != nil {
+			err = w.trigger.Close()
+		}
+	})
+	return err
+}
+
+// The run method runs the watching loop until the watcher is closed.
+func (w *fileWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var c <-chan struct{}
+	if w.trigger != nil {
+		c = w.trigger.C()
+	}
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		case <-c:
+			// The subdirectories created later are watched before rescanning.
+			w.watchDirs()
+		}
+		w.check()
+	}
+}
+
+// The check method rescans the config files and reports the changes.
+func (w *fileWatcher) check() {
+	states := w.loader.rescan()
+	if len(diffStates(w.states, states)) > 0 {
+		// Wait until the config files settle, the changes are merged by comparing
+		// the final states with the previous states.
+		deadline := time.Now().Add(watchSettleMax)
+		for time.Now().Before(deadline) {
+			select {
+			case <-w.done:
+				return
+			case <-time.After(watchSettle):
+			}
+			next := w.loader.rescan()
+			if len(diffStates(states, next)) == 0 {
+				break
+			}
+			states = next
+		}
+	}
+	events := diffStates(w.states, states)
+	w.states = states
+	if w.trigger != nil && len(events) > 0 {
+		w.watchDirs()
+	}
+	for i, j := 0, len(events); i < j; i++ {
+		select {
+		case <-w.done:
+			return
+		default:
+			if w.fn != nil {
+				w.fn(events[i])
+			}
+		}
+	}
+}
+
+// The watchDirs method adds the directories of the added patterns and the
+// registered config files to the trigger. For the patterns containing "**", all
+// the subdirectories of the pattern directories are also added.
+func (w *fileWatcher) watchDirs() {
+	w.loader.mutex.RLock()
+	patterns := w.loader.patterns
+	w.loader.mutex.RUnlock()
+
+	dirs := make(map[string]bool)
+	for _, pattern := range patterns {
+		for _, glob := range expandBraces(pattern.glob()) {
+			// Find the longest directory prefix of the pattern without meta characters.
+			dir := filepath.Dir(glob)
+			for hasMeta(dir) {
+				dir = filepath.Dir(dir)
+			}
+			dirs[dir] = true
+			if strings.Contains(glob, "**") {
+				walkDirs(dir, dirs)
+			}
+		}
+	}
+	for path := range w.states {
+		dirs[filepath.Dir(path)] = true
+	}
+	for dir := range dirs {
+		// The directory may not exist yet, it will be retried next time.
+		_ = w.trigger.Add(dir)
+	}
+}
+
+// The walkDirs function adds all the subdirectories of the given directory to
+// the given directory set, the inaccessible directories are skipped.
+func walkDirs(root string, dirs map[string]bool) {
+	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if info != nil && info.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			dirs[path] = true
+		}
+		return nil
+	})
+}
+
+// The hasMeta function determines whether the given path contains any of the
+// magic characters recognized by filepath.Match.
+func hasMeta(path string) bool {
+	magic := `*?[`
+	if filepath.Separator == '/' {
+		magic = `*?[\`
+	}
+	return strings.ContainsAny(path, magic)
+}
+
+// The diffStates function compares the given config file states and returns the
+// change events sorted by path.
+func diffStates(old, new map[string]fileState) []Event {
+	var events []Event
+	for path, n := range new {
+		o, found := old[path]
+		switch {
+		case !found:
+			events = append(events, Event{Target: n.target, Path: path, Op: Create})
+		case !os.SameFile(o.info, n.info):
+			events = append(events, Event{Target: n.target, Path: path, Op: Rename})
+		case !o.info.ModTime().Equal(n.info.ModTime()) || o.info.Size() != n.info.Size():
+			events = append(events, Event{Target: n.target, Path: path, Op: Write})
+		}
+	}
+	for path, o := range old {
+		if _, found := new[path]; found {
+			continue
+		}
+		op := Remove
+		for _, n := range new {
+			// The config file was moved to another path.
+			if os.SameFile(o.info, n.info) {
+				op = Rename
+				break
+			}
+		}
+		events = append(events, Event{Target: o.target, Path: path, Op: op})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	return events
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package configurator
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// The inotifyMask constant defines the inotify events we care about.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ATTRIB | syscall.IN_DELETE_SELF
+
+// The newTrigger function creates and returns an inotify based trigger.
+func newTrigger() (trigger, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	// The non-blocking file descriptor is managed by the runtime poller, so that
+	// the blocked read can be interrupted by closing the file.
+	t := &inotifyTrigger{
+		fd:   fd,
+		file: os.NewFile(uintptr(fd), "inotify"),
+		c:    make(chan struct{}, 1),
+		dirs: make(map[string]bool),
+	}
+	go t.run()
+	return t, nil
+}
+
+// The inotifyTrigger type is an inotify based implementation of the trigger interface.
+type inotifyTrigger struct {
+	mutex sync.Mutex
+	fd    int
+	file  *os.File
+	c     chan struct{}
+	dirs  map[string]bool
+}
+
+// C returns the channel that receives a value when the watched directories change.
+func (t *inotifyTrigger) C() <-chan struct{} {
+	return t.c
+}
+
+// Add adds the given directory to the watch list.
+func (t *inotifyTrigger) Add(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.dirs[dir] {
+		return nil
+	}
+	if _, err := syscall.InotifyAddWatch(t.fd, dir, inotifyMask); err != nil {
+		return err
+	}
+	t.dirs[dir] = true
+	return nil
+}
+
+// Close closes the trigger.
+func (t *inotifyTrigger) Close() error {
+	return t.file.Close()
+}
+
+// The run method reads the inotify events until the trigger is closed.
+func (t *inotifyTrigger) run() {
+	buf := make([]byte, syscall.SizeofInotifyEvent*256)
+	for {
+		if _, err := t.file.Read(buf); err != nil {
+			return
+		}
+		// The events themselves are not parsed, the watcher rescans the config
+		// files to find the changes.
+		select {
+		case t.c <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2020 The ZKits Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configurator
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOp_String(t *testing.T) {
+	if got := (Create | Rename).String(); got != "CREATE|RENAME" {
+		t.Fatalf("Op.String(): %s", got)
+	}
+	if got := (Write | Remove).String(); got != "WRITE|REMOVE" {
+		t.Fatalf("Op.String(): %s", got)
+	}
+}
+
+func testFileLoaderWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.json", `{"name":"a"}`)
+
+	l := NewFileLoader().MustAddFile(filepath.Join(dir, "*.json"))
+	events := make(chan Event, 16)
+	w, err := l.Watch(10*time.Millisecond, func(e Event) { events <- e })
+	if err != nil {
+		t.Fatalf("FileLoader.Watch(): %s", err)
+	}
+	defer w.Close()
+
+	// The changes of a single write are usually merged into one event, but a slow
+	// write may still be reported as an extra Write event, which is skipped.
+	wait := func(target string, op Op) {
+		for {
+			select {
+			case e := <-events:
+				if e.Op == Write && op != Write {
+					continue
+				}
+				if e.Target != target || e.Op != op || e.Path != filepath.Join(dir, target+".json") {
+					t.Fatalf("FileLoader.Watch(): %+v", e)
+				}
+				return
+			case <-time.After(5 * time.Second):
+				t.Fatalf("FileLoader.Watch(): timeout %s %s", target, op)
+			}
+		}
+	}
+
+	write("a.json", `{"name":"a2"}`)
+	wait("a", Write)
+
+	write("b.json", `{"name":"b"}`)
+	wait("b", Create)
+	if item, err := l.Load("b"); err != nil || item == nil {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+
+	write("a.tmp", `{"name":"a3"}`)
+	if err := os.Rename(filepath.Join(dir, "a.tmp"), filepath.Join(dir, "a.json")); err != nil {
+		t.Fatal(err)
+	}
+	wait("a", Rename)
+
+	if err := os.Remove(filepath.Join(dir, "b.json")); err != nil {
+		t.Fatal(err)
+	}
+	wait("b", Remove)
+	if item, err := l.Load("b"); err != nil || item != nil {
+		t.Fatalf("FileLoader.Load(): %v %v", item, err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Watcher.Close(): %s", err)
+	}
+	write("c.json", `{"name":"c"}`)
+	select {
+	case e := <-events:
+		t.Fatalf("FileLoader.Watch(): %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFileLoader_Watch(t *testing.T) {
+	testFileLoaderWatch(t)
+}
+
+func TestFileLoader_WatchPolling(t *testing.T) {
+	defer func(f func() (trigger, error)) { newWatchTrigger = f }(newWatchTrigger)
+	newWatchTrigger = func() (trigger, error) { return nil, errors.New("test") }
+
+	testFileLoaderWatch(t)
+}
+
+func TestFileLoader_WatchSettle(t *testing.T) {
+	defer func(d time.Duration) { watchSettle = d }(watchSettle)
+	watchSettle = 200 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l := NewFileLoader().MustAddFile(filepath.Join(dir, "*.json"))
+	events := make(chan Event, 16)
+	w, err := l.Watch(10*time.Millisecond, func(e Event) { events <- e })
+	if err != nil {
+		t.Fatalf("FileLoader.Watch(): %s", err)
+	}
+	defer w.Close()
+
+	// The config file is created empty and written within the settle window.
+	path := filepath.Join(dir, "a.json")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := ioutil.WriteFile(path, []byte(`{"name":"a"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-events:
+		if e.Target != "a" || e.Op != Create {
+			t.Fatalf("FileLoader.Watch(): %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("FileLoader.Watch(): timeout")
+	}
+	select {
+	case e := <-events:
+		t.Fatalf("FileLoader.Watch(): %+v", e)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestConfigurator_Watch(t *testing.T) {
+	o := New()
+	if err := o.AddFile("test/*.json"); err != nil {
+		t.Fatal(err)
+	}
+	w, err := o.Watch(0, nil)
+	if err != nil {
+		t.Fatalf("Configurator.Watch(): %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Watcher.Close(): %s", err)
+	}
+}
+
+// The testTrigger type is a trigger that records the added directories.
+type testTrigger struct {
+	mutex sync.Mutex
+	c     chan struct{}
+	dirs  map[string]bool
+}
+
+func (t *testTrigger) C() <-chan struct{} { return t.c }
+
+func (t *testTrigger) Add(dir string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.dirs[dir] = true
+	return nil
+}
+
+func (t *testTrigger) Close() error { return nil }
+
+func (t *testTrigger) has(dir string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.dirs[dir]
+}
+
+func TestFileLoader_WatchRecursive(t *testing.T) {
+	tt := &testTrigger{c: make(chan struct{}, 1), dirs: make(map[string]bool)}
+	defer func(f func() (trigger, error)) { newWatchTrigger = f }(newWatchTrigger)
+	newWatchTrigger = func() (trigger, error) { return tt, nil }
+
+	dir, err := ioutil.TempDir("", "configurator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "foo", "bar"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewFileLoader().MustAddFile(filepath.Join(dir, "**", "*.json"))
+	w, err := l.Watch(time.Hour, nil)
+	if err != nil {
+		t.Fatalf("FileLoader.Watch(): %s", err)
+	}
+	defer w.Close()
+
+	for _, sub := range []string{"", "foo", filepath.Join("foo", "bar")} {
+		if !tt.has(filepath.Join(dir, sub)) {
+			t.Fatalf("FileLoader.Watch(): %q not watched", sub)
+		}
+	}
+
+	// The subdirectories created later are watched when the trigger fires.
+	baz := filepath.Join(dir, "foo", "baz")
+	if err := os.Mkdir(baz, 0755); err != nil {
+		t.Fatal(err)
+	}
+	tt.c <- struct{}{}
+	deadline := time.Now().Add(5 * time.Second)
+	for !tt.has(baz) {
+		if time.Now().After(deadline) {
+			t.Fatal("FileLoader.Watch(): timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}